@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/netip"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func init() {
+	operation.DefaultRegistry.MustRegister("akscnisubnet", func() operation.CidrOperation { return &aksCNISubnetOperation{} })
+}
+
+const (
+	// aksReservedAddresses is the number of addresses Azure reserves in
+	// every subnet.
+	aksReservedAddresses = 5
+
+	// aksSurgeNodes is the number of extra nodes an upgrade of the node pool
+	// adds.
+	aksSurgeNodes = 1
+)
+
+// aksCNISubnetOperation sizes the node subnet of an AKS cluster using Azure
+// CNI, where every node and every pod gets an IP of the subnet, and allocates
+// it at the start of the prefix.
+type aksCNISubnetOperation struct {
+	prefix      string
+	network     v1beta1.K8sNetwork
+	pool        netip.Prefix
+	requiredIPs int64
+	maxNodes    int64
+	subnet      netip.Prefix
+}
+
+func (o *aksCNISubnetOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, validateK8sNetworkParameters(p, validateK8sNodes)...)
+}
+
+func (o *aksCNISubnetOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	if p.K8sNetwork != nil {
+		o.network = *p.K8sNetwork
+	}
+	if err := r.Into("k8sNetwork", p.K8sNetworkField, &o.network); err != nil {
+		return err
+	}
+	if errs := validateK8sNodes(field.NewPath("k8sNetwork"), o.network); len(errs) > 0 {
+		return errors.Wrapf(errs.ToAggregate(), "invalid k8sNetwork for %s", r.Kind())
+	}
+	return nil
+}
+
+// Compute sizes the subnet for the IPs of every node, including a surge node
+// for upgrades, and of maxPodsPerNode pods per node, plus the addresses Azure
+// reserves.
+func (o *aksCNISubnetOperation) Compute() error {
+	var err error
+	if o.pool, err = cidr.ParsePrefix(o.prefix); err != nil {
+		return err
+	}
+	if !o.pool.Addr().Is4() {
+		return &cidr.InvalidPrefixError{Prefix: o.prefix, Err: errors.New("must be an IPv4 prefix")}
+	}
+
+	ipsPerNode := o.network.MaxPodsPerNode + 1
+	o.requiredIPs = (o.network.NodeCount + aksSurgeNodes) * ipsPerNode
+	length := cidr.Bits32 - bitsFor(o.requiredIPs+aksReservedAddresses)
+	if length < o.pool.Bits() {
+		return &PrefixTooSmallError{Prefix: o.pool, Name: "node", Length: length}
+	}
+
+	// The subnet is the whole prefix if it has the exact size.
+	if o.subnet, err = cidr.Subnet(o.pool, length-o.pool.Bits(), 0); err != nil {
+		return err
+	}
+	o.maxNodes = (int64(1)<<(cidr.Bits32-length)-aksReservedAddresses)/ipsPerNode - aksSurgeNodes
+	return nil
+}
+
+func (o *aksCNISubnetOperation) Render() (any, error) {
+	return map[string]any{
+		"subnetCIDR":  o.subnet.String(),
+		"requiredIPs": o.requiredIPs,
+		"maxNodes":    o.maxNodes,
+		"maxPods":     o.network.MaxPodsPerNode,
+	}, nil
+}
+
+func (o *aksCNISubnetOperation) Intermediates() map[string]any {
+	return map[string]any{"pool": o.pool.String()}
+}
+
+func (o *aksCNISubnetOperation) Allocations() map[netip.Prefix][]netip.Prefix {
+	return map[netip.Prefix][]netip.Prefix{o.pool: {o.subnet}}
+}
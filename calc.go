@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alecthomas/kong"
+	"google.golang.org/protobuf/types/known/structpb"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composite"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+)
+
+// Output formats supported by the calc command.
+const (
+	outputYAML  = "yaml"
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+// defaultOutputField is the field of the composite resource results are
+// written to unless the outputField parameter is set.
+const defaultOutputField = "status.atFunction.cidr"
+
+// defaultXRKind is the kind errors are reported for when calc runs without a
+// composite resource or the composite resource has no kind.
+const defaultXRKind = "calc"
+
+// CalcCmd runs a cidrFunc locally, without a Crossplane control plane.
+type CalcCmd struct {
+	CidrFunc string `arg:"" optional:"" help:"The cidrFunc to run. Overrides the cidrFunc of the Parameters file."`
+
+	Parameters string `short:"f" type:"existingfile" placeholder:"PATH" help:"A YAML file containing the Parameters to run the cidrFunc with."`
+	XR         string `type:"existingfile" placeholder:"PATH" help:"A YAML file containing the composite resource *Field parameters are read from."`
+	Output     string `short:"o" enum:"yaml,json,table" default:"yaml" help:"Output format of the result. One of yaml, json or table."`
+
+	Prefix      string   `help:"The CIDR block used as input for the calculation."`
+	NewBits     []int    `name:"newbits" help:"Comma separated number of bits to extend the prefix by."`
+	NetNum      *int64   `help:"The network number of cidrsubnet."`
+	NetNumCount *int64   `help:"The number of networks cidrsubnetloop creates."`
+	NetNumItems []string `help:"Comma separated items cidrsubnetloop creates a network for."`
+	HostNum     *int     `help:"The host number of cidrhost."`
+	Offset      *int     `help:"The network number cidrsubnetloop starts at."`
+	HashKey     string   `help:"The key cidrsubnethash hashes into a network number."`
+	UsedCIDRs   []string `name:"used-cidrs" help:"Comma separated CIDR blocks cidrsubnethash, cidrsubnetloop and cidrsubnets must not overlap."`
+	Seed        string   `help:"The seed ulaprefix derives its prefix from."`
+	MAC         string   `name:"mac" help:"The MAC address eui64 builds an address from."`
+	IPv4Address string   `name:"ipv4-address" help:"The IPv4 address nat64 and ipv4mapped embed."`
+}
+
+// Run the calc command.
+func (c *CalcCmd) Run(k *kong.Context) error {
+	in, err := c.parameters()
+	if err != nil {
+		return err
+	}
+
+	xr, err := readXR(c.XR)
+	if err != nil {
+		return err
+	}
+	if kind, _ := xr["kind"].(string); kind == "" {
+		xr["kind"] = defaultXRKind
+	}
+
+	req, err := NewRunFunctionRequest(in, xr)
+	if err != nil {
+		return err
+	}
+
+	value, err := calculate(context.Background(), &Function{log: logging.NewNopLogger()}, req, in.OutputField)
+	if err != nil {
+		return err
+	}
+
+	return writeValue(k.Stdout, c.Output, value)
+}
+
+// parameters returns the Parameters read from the parameters file, overridden
+// by any flags that were set.
+func (c *CalcCmd) parameters() (*v1beta1.Parameters, error) {
+	in := &v1beta1.Parameters{}
+	if c.Parameters != "" {
+		if err := readYAML(c.Parameters, in); err != nil {
+			return nil, errors.Wrap(err, "cannot read Parameters")
+		}
+	}
+
+	if c.CidrFunc != "" {
+		in.CidrFunc = c.CidrFunc
+		in.CidrFuncField = ""
+	}
+	if c.Prefix != "" {
+		in.Prefix = c.Prefix
+		in.PrefixField = ""
+	}
+	if len(c.NewBits) > 0 {
+		in.NewBits = c.NewBits
+		in.NewBitsField = ""
+	}
+	if c.NetNum != nil {
+		in.NetNum = *c.NetNum
+		in.NetNumField = ""
+	}
+	if c.NetNumCount != nil {
+		in.NetNumCount = *c.NetNumCount
+		in.NetNumCountField = ""
+	}
+	if len(c.NetNumItems) > 0 {
+		in.NetNumItems = c.NetNumItems
+		in.NetNumItemsField = ""
+	}
+	if c.HostNum != nil {
+		in.HostNum = *c.HostNum
+		in.HostNumField = ""
+	}
+	if c.Offset != nil {
+		in.Offset = *c.Offset
+		in.OffsetField = ""
+	}
+	if c.HashKey != "" {
+		in.HashKey = c.HashKey
+		in.HashKeyField = ""
+	}
+	if len(c.UsedCIDRs) > 0 {
+		in.UsedCIDRs = c.UsedCIDRs
+		in.UsedCIDRsField = ""
+	}
+	if c.Seed != "" {
+		in.Seed = c.Seed
+		in.SeedField = ""
+	}
+	if c.MAC != "" {
+		in.MAC = c.MAC
+		in.MACField = ""
+	}
+	if c.IPv4Address != "" {
+		in.IPv4Address = c.IPv4Address
+		in.IPv4AddressField = ""
+	}
+
+	if in.CidrFunc == "" && in.CidrFuncField == "" {
+		return nil, errors.Errorf("a cidrFunc is required, supported functions are %v", SupportedCidrFuncs())
+	}
+	return in, nil
+}
+
+// NewRunFunctionRequest returns a RunFunctionRequest that runs the Function
+// with the supplied input against the supplied observed composite resource.
+func NewRunFunctionRequest(in *v1beta1.Parameters, xr map[string]any) (*fnv1.RunFunctionRequest, error) {
+	input, err := toStruct(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert Parameters")
+	}
+	oxr, err := structpb.NewStruct(xr)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert composite resource")
+	}
+	return &fnv1.RunFunctionRequest{
+		Input: input,
+		Observed: &fnv1.State{
+			Composite: &fnv1.Resource{Resource: oxr},
+		},
+	}, nil
+}
+
+// calculate runs the Function and returns the value it wrote to the output
+// field of the desired composite resource.
+func calculate(ctx context.Context, f *Function, req *fnv1.RunFunctionRequest, outputField string) (any, error) {
+	rsp, err := f.RunFunction(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rsp.GetResults() {
+		if r.GetSeverity() == fnv1.Severity_SEVERITY_FATAL {
+			return nil, errors.New(r.GetMessage())
+		}
+	}
+
+	dxr := composite.New()
+	if err := resource.AsObject(rsp.GetDesired().GetComposite().GetResource(), dxr); err != nil {
+		return nil, errors.Wrap(err, "cannot read desired composite resource")
+	}
+	if outputField == "" {
+		outputField = defaultOutputField
+	}
+	value, err := dxr.GetValue(outputField)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get result from field %s", outputField)
+	}
+	return value, nil
+}
+
+// readXR reads a composite resource from the supplied YAML file. It returns
+// an empty composite resource if no file is supplied.
+func readXR(path string) (map[string]any, error) {
+	xr := map[string]any{"apiVersion": "", "kind": ""}
+	if path == "" {
+		return xr, nil
+	}
+	if err := readYAML(path, &xr); err != nil {
+		return nil, errors.Wrap(err, "cannot read composite resource")
+	}
+	return xr, nil
+}
+
+// readYAML decodes the YAML file at path into the supplied pointer.
+func readYAML(path string, into any) error {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(yaml.Unmarshal(b, into), "cannot decode %s", path)
+}
+
+// toStruct converts the supplied object to a protobuf struct.
+func toStruct(o any) (*structpb.Struct, error) {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	return s, s.UnmarshalJSON(b)
+}
+
+// writeValue writes the supplied value to w in the supplied format.
+func writeValue(w io.Writer, format string, value any) error {
+	switch format {
+	case outputJSON:
+		b, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case outputTable:
+		return writeTable(w, value)
+	default:
+		b, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+}
+
+// writeTable writes the supplied value as a table. Lists are written with
+// their index, maps with their key. Nested values are written as JSON.
+func writeTable(w io.Writer, value any) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	switch v := value.(type) {
+	case []any:
+		fmt.Fprintln(tw, "INDEX\tVALUE")
+		for i, item := range v {
+			fmt.Fprintf(tw, "%d\t%s\n", i, tableCell(item))
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintln(tw, "KEY\tVALUE")
+		for _, k := range keys {
+			fmt.Fprintf(tw, "%s\t%s\n", k, tableCell(v[k]))
+		}
+	default:
+		fmt.Fprintln(tw, "VALUE")
+		fmt.Fprintln(tw, tableCell(v))
+	}
+	return tw.Flush()
+}
+
+// tableCell returns the table representation of a single value.
+func tableCell(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []any:
+		s := make([]string, len(t))
+		for i := range t {
+			s[i] = tableCell(t[i])
+		}
+		return strings.Join(s, ", ")
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCalcCmd(t *testing.T) {
+	type want struct {
+		out string
+		err string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   []string
+		want   want
+	}{
+		"CidrSubnetsYAML": {
+			reason: "should print the subnets of the documented invocation as YAML",
+			args:   []string{"calc", "cidrsubnets", "--prefix", "10.0.0.0/16", "--newbits", "8,8,4"},
+			want:   want{out: "- 10.0.0.0/24\n- 10.0.1.0/24\n- 10.0.16.0/20\n"},
+		},
+		"CidrHostJSON": {
+			reason: "should print the host as JSON",
+			args:   []string{"calc", "cidrhost", "--prefix", "10.0.0.0/24", "--host-num", "5", "-o", "json"},
+			want:   want{out: "\"10.0.0.5\"\n"},
+		},
+		"CidrSubnetLoopTable": {
+			reason: "should print the subnets as a table",
+			args:   []string{"calc", "cidrsubnetloop", "--prefix", "10.0.0.0/16", "--newbits", "8", "--net-num-items", "a,b", "-o", "table"},
+			want:   want{out: "INDEX  VALUE\n0      10.0.0.0/24\n1      10.0.1.0/24\n"},
+		},
+		"ParametersFile": {
+			reason: "should read *Field parameters from the composite resource",
+			args:   []string{"calc", "cidrsubnets", "--xr", "examples/xr-cidrsubnets.yaml", "-f", "testdata/parameters-cidrsubnets.yaml"},
+			want:   want{out: "- 10.0.0.0/28\n- 10.0.1.0/24\n- 10.0.4.0/22\n"},
+		},
+		"Fatal": {
+			reason: "should return an error when the cidrFunc fails",
+			args:   []string{"calc", "cidrsubnets", "--prefix", "10.0.0.0/24", "--newbits", "1,1,1"},
+			want:   want{err: "cannot calculate cidrsubnets for calc: not enough remaining address space in 10.0.0.0/24 for a subnet with a prefix of 25 bits after 10.0.0.128/25"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			parser := kong.Must(&CLI{}, kong.Writers(out, out))
+			ctx, err := parser.Parse(tc.args)
+			if err != nil {
+				t.Fatalf("parser.Parse(...): %v", err)
+			}
+
+			err = ctx.Run()
+			if tc.want.err != "" {
+				if err == nil || err.Error() != tc.want.err {
+					t.Errorf("%s\nctx.Run(): want error %q, got %v", tc.reason, tc.want.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s\nctx.Run(): unexpected error: %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.out, out.String()); diff != "" {
+				t.Errorf("%s\nctx.Run(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
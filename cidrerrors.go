@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/response"
+)
+
+// conditionCIDRsAllocated is the type of the condition the Function sets on
+// the composite resource to describe the outcome of its calculation.
+const conditionCIDRsAllocated = "CIDRsAllocated"
+
+// Reasons of results and conditions that are not reported by the cidr
+// package.
+const (
+	reasonAllocated    = "Allocated"
+	reasonInvalidInput = "InvalidInput"
+	reasonInternal     = "InternalError"
+)
+
+// A CalculationError is returned by the CIDR calculation functions of the
+// cidr package. Its reason is propagated to the fatal result of the function.
+type CalculationError interface {
+	error
+	Reason() string
+}
+
+// fatal adds a fatal result to the supplied response. If err wraps a
+// CalculationError its reason is set on the result.
+func fatal(rsp *fnv1.RunFunctionResponse, err error) {
+	response.Fatal(rsp, err)
+
+	var ce CalculationError
+	if errors.As(err, &ce) {
+		setReason(rsp, ce.Reason())
+	}
+}
+
+// fatalInput adds a fatal result caused by invalid function input to the
+// supplied response.
+func fatalInput(rsp *fnv1.RunFunctionResponse, err error) {
+	response.Fatal(rsp, err)
+	setReason(rsp, reasonInvalidInput)
+}
+
+// setReason sets the reason of the last result of the supplied response.
+func setReason(rsp *fnv1.RunFunctionResponse, reason string) {
+	rsp.Results[len(rsp.Results)-1].Reason = &reason
+}
+
+// setAllocatedCondition sets the CIDRsAllocated condition of the composite
+// resource to true.
+func setAllocatedCondition(rsp *fnv1.RunFunctionResponse, cidrFunc, outputField string) {
+	response.ConditionTrue(rsp, conditionCIDRsAllocated, reasonAllocated).
+		WithMessage(fmt.Sprintf("cidrFunc %s wrote its result to %s", cidrFunc, outputField)).
+		TargetComposite()
+}
+
+// setFailedCondition sets the CIDRsAllocated condition of the composite
+// resource to false, with the reason and message of the first fatal result of
+// the supplied response. It does nothing if there is no fatal result.
+func setFailedCondition(rsp *fnv1.RunFunctionResponse) {
+	for _, r := range rsp.GetResults() {
+		if r.GetSeverity() != fnv1.Severity_SEVERITY_FATAL {
+			continue
+		}
+		reason := r.GetReason()
+		if reason == "" {
+			reason = reasonInternal
+		}
+		response.ConditionFalse(rsp, conditionCIDRsAllocated, reason).WithMessage(r.GetMessage()).TargetComposite()
+		return
+	}
+}
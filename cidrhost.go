@@ -1,27 +1,74 @@
 package main
 
 import (
-	"fmt"
-	"math/big"
-	"net"
+	"net/netip"
 
-	"github.com/apparentlymart/go-cidr/cidr"
-	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/crossplane/function-sdk-go/resource"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
 )
 
-func CidrHost(prefix string, hostNumber int) (string, error) {
-	hostNum := big.NewInt(int64(hostNumber))
+func init() {
+	operation.DefaultRegistry.MustRegister("cidrhost", func() operation.CidrOperation { return &cidrHostOperation{} })
+}
 
-	_, network, err := net.ParseCIDR(prefix)
-	if err != nil {
-		errTxt := fmt.Sprintf("invalid CIDR expression: %s", err)
-		return "", errors.New(errTxt)
+// ValidateCidrHostParameters validates the Parameters object
+// in the context of cidrhost
+func ValidateCidrHostParameters(p *v1beta1.Parameters, oxr *resource.Composite) field.ErrorList {
+	path := field.NewPath("parameters")
+	if p.HostNum > 0 && len(p.HostNumField) > 0 {
+		return field.ErrorList{field.Forbidden(path.Child("hostNumField"), "specify only one of hostnum or hostnumfield to avoid ambiguous function input")}
 	}
+	if p.HostNum == 0 {
+		if p.HostNumField == "" {
+			return field.ErrorList{field.Required(path.Child("hostNum"), "either hostnum or hostnumfield function input is required")}
+		}
+		if oxr == nil {
+			return nil
+		}
+		if _, err := oxr.Resource.GetInteger(p.HostNumField); err != nil {
+			return field.ErrorList{field.Invalid(path.Child("hostNumField"), p.HostNumField, "cannot get hostnum at hostnumfield")}
+		}
+	}
+
+	return nil
+}
 
-	ip, err := cidr.HostBig(network, hostNum)
+// cidrHostOperation calculates the host CIDR from a prefix and a host number.
+// https://developer.hashicorp.com/terraform/language/functions/cidrhost
+type cidrHostOperation struct {
+	prefix  string
+	hostNum int64
+	host    netip.Addr
+}
+
+func (o *cidrHostOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, ValidateCidrHostParameters(p, r.Composite())...)
+}
+
+func (o *cidrHostOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	o.hostNum, err = r.Int("hostNum", int64(p.HostNum), p.HostNumField)
+	return err
+}
+
+func (o *cidrHostOperation) Compute() error {
+	prefix, err := cidr.ParsePrefix(o.prefix)
 	if err != nil {
-		return "", err
+		return err
 	}
+	o.host, err = cidr.Host(prefix, o.hostNum)
+	return err
+}
 
-	return ip.String(), nil
+func (o *cidrHostOperation) Render() (any, error) {
+	return o.host.String(), nil
 }
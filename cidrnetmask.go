@@ -1,17 +1,46 @@
 package main
 
 import (
-	"fmt"
-	"net"
+	"net/netip"
 
-	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
 )
 
-func CidrNetmask(prefix string) (string, error) {
-	_, network, err := net.ParseCIDR(prefix)
+func init() {
+	operation.DefaultRegistry.MustRegister("cidrnetmask", func() operation.CidrOperation { return &cidrNetmaskOperation{} })
+}
+
+// cidrNetmaskOperation calculates the netmask from a prefix.
+// https://developer.hashicorp.com/terraform/language/functions/cidrnetmask
+type cidrNetmaskOperation struct {
+	prefix  string
+	netmask netip.Addr
+}
+
+// Validate only validates the prefix, which is the sole input of cidrnetmask.
+func (o *cidrNetmaskOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	return ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+}
+
+func (o *cidrNetmaskOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	o.prefix, err = r.Prefix(p.Prefix, p.PrefixField)
+	return err
+}
+
+func (o *cidrNetmaskOperation) Compute() error {
+	prefix, err := cidr.ParsePrefix(o.prefix)
 	if err != nil {
-		errTxt := fmt.Sprintf("invalid CIDR expression: %s", err)
-		return "", errors.New(errTxt)
+		return err
 	}
-	return net.IP(network.Mask).String(), nil
+	o.netmask = cidr.Netmask(prefix)
+	return nil
+}
+
+func (o *cidrNetmaskOperation) Render() (any, error) {
+	return o.netmask.String(), nil
 }
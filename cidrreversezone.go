@@ -0,0 +1,45 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func init() {
+	operation.DefaultRegistry.MustRegister("cidrreversezone", func() operation.CidrOperation { return &cidrReverseZoneOperation{} })
+}
+
+// cidrReverseZoneOperation calculates the names of the reverse DNS zones that
+// cover a prefix.
+type cidrReverseZoneOperation struct {
+	prefix string
+	zones  []string
+}
+
+// Validate only validates the prefix, which is the sole input of
+// cidrreversezone.
+func (o *cidrReverseZoneOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	return ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+}
+
+func (o *cidrReverseZoneOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	o.prefix, err = r.Prefix(p.Prefix, p.PrefixField)
+	return err
+}
+
+func (o *cidrReverseZoneOperation) Compute() error {
+	prefix, err := cidr.ParsePrefix(o.prefix)
+	if err != nil {
+		return err
+	}
+	o.zones = cidr.ReverseZones(prefix)
+	return nil
+}
+
+func (o *cidrReverseZoneOperation) Render() (any, error) {
+	return o.zones, nil
+}
@@ -1,26 +1,88 @@
 package main
 
 import (
-	"fmt"
-	"math/big"
-	"net"
+	"net/netip"
 
-	"github.com/apparentlymart/go-cidr/cidr"
-	"github.com/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
 )
 
-// CidrSubnet
-func CidrSubnet(prefix string, newbits int, netnum int64) ([]byte, error) {
-	_, network, err := net.ParseCIDR(prefix)
-	if err != nil {
-		errStr := fmt.Sprintf("prefix: %s, newbits: %d, netnum: %d", prefix, newbits, netnum)
-		return nil, errors.Wrap(err, errStr)
+func init() {
+	operation.DefaultRegistry.MustRegister("cidrsubnet", func() operation.CidrOperation { return &cidrSubnetOperation{} })
+}
+
+// ValidateCidrSubnetParameters validates the Parameters object
+// in the context of cidrsubnet
+func ValidateCidrSubnetParameters(p *v1beta1.Parameters) field.ErrorList {
+	path := field.NewPath("parameters")
+	var errs field.ErrorList
+
+	switch {
+	case len(p.NewBits) > 0 && len(p.NewBitsField) > 0:
+		errs = append(errs, field.Forbidden(path.Child("newBitsField"), "specify only one of newbits or newbitsfield to avoid ambiguous function input"))
+	case len(p.NewBits) == 0 && p.NewBitsField == "":
+		errs = append(errs, field.Required(path.Child("newBits"), "either newbits or newbitsfield function input is required"))
+	case p.NewBitsField == "" && len(p.NewBits) != 1:
+		errs = append(errs, field.Invalid(path.Child("newBits"), p.NewBits, "cidrFunc cidrsubnet requires exactly 1 parameter in the array"))
 	}
+	errs = append(errs, validateNewBits(path.Child("newBits"), p.NewBits, 0, addressBits(p.Prefix))...)
 
-	newNetwork, err := cidr.SubnetBig(network, newbits, big.NewInt(netnum))
-	if err != nil {
-		errStr := fmt.Sprintf("cidr.SubnetBig: %v", err)
-		return nil, errors.New(errStr)
+	if p.NetNum > 0 && len(p.NetNumField) > 0 {
+		errs = append(errs, field.Forbidden(path.Child("netNumField"), "cidrFunc cidrsubnet requires either one of netnum or netnumfield"))
 	}
-	return []byte(newNetwork.String()), nil
+
+	return errs
+}
+
+// cidrSubnetOperation calculates a subnet CIDR from a prefix, a net number
+// and a new bits.
+// https://developer.hashicorp.com/terraform/language/functions/cidrsubnet
+type cidrSubnetOperation struct {
+	prefix  string
+	newBits []int
+	netNum  int64
+	pool    netip.Prefix
+	subnet  netip.Prefix
+}
+
+func (o *cidrSubnetOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, ValidateCidrSubnetParameters(p)...)
+}
+
+func (o *cidrSubnetOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	o.newBits = p.NewBits
+	if err := r.Into("newBits", p.NewBitsField, &o.newBits); err != nil {
+		return err
+	}
+	if len(o.newBits) == 0 {
+		return errors.Errorf("cidrFunc cidrsubnet requires newbits for %s", r.Kind())
+	}
+	o.netNum, err = r.Int("netNum", p.NetNum, p.NetNumField)
+	return err
+}
+
+func (o *cidrSubnetOperation) Compute() error {
+	var err error
+	if o.pool, err = cidr.ParsePrefix(o.prefix); err != nil {
+		return err
+	}
+	o.subnet, err = cidr.Subnet(o.pool, o.newBits[0], o.netNum)
+	return err
+}
+
+func (o *cidrSubnetOperation) Render() (any, error) {
+	return o.subnet.String(), nil
+}
+
+func (o *cidrSubnetOperation) Intermediates() map[string]any {
+	return map[string]any{"pool": o.pool.String(), "newBits": o.newBits[0]}
 }
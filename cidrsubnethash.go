@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/netip"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func init() {
+	operation.DefaultRegistry.MustRegister("cidrsubnethash", func() operation.CidrOperation { return &cidrSubnetHashOperation{} })
+}
+
+// ValidateCidrSubnetHashParameters validates the Parameters object
+// in the context of cidrsubnethash
+func ValidateCidrSubnetHashParameters(p *v1beta1.Parameters) field.ErrorList {
+	path := field.NewPath("parameters")
+	var errs field.ErrorList
+
+	switch {
+	case len(p.NewBits) > 0 && len(p.NewBitsField) > 0:
+		errs = append(errs, field.Forbidden(path.Child("newBitsField"), "specify only one of newbits or newbitsfield to avoid ambiguous function input"))
+	case len(p.NewBits) == 0 && p.NewBitsField == "":
+		errs = append(errs, field.Required(path.Child("newBits"), "either newbits or newbitsfield function input is required"))
+	case p.NewBitsField == "" && len(p.NewBits) != 1:
+		errs = append(errs, field.Invalid(path.Child("newBits"), p.NewBits, "cidrFunc cidrsubnethash requires exactly 1 parameter in the array"))
+	}
+	errs = append(errs, validateNewBits(path.Child("newBits"), p.NewBits, 0, min(addressBits(p.Prefix), cidr.MaxHashNewBits))...)
+
+	if p.HashKey != "" && p.HashKeyField != "" {
+		errs = append(errs, field.Forbidden(path.Child("hashKeyField"), "specify only one of hashKey or hashKeyField to avoid ambiguous function input"))
+	}
+	errs = append(errs, validateUsedCIDRs(p)...)
+	if p.MaxProbes < 0 {
+		errs = append(errs, field.Invalid(path.Child("maxProbes"), p.MaxProbes, "maxProbes must not be negative"))
+	}
+
+	return errs
+}
+
+// cidrSubnetHashOperation calculates a subnet CIDR from a prefix, a new bits
+// and the netNum a key hashes to, skipping subnets that are already used.
+type cidrSubnetHashOperation struct {
+	prefix    string
+	newBits   []int
+	key       string
+	usedCIDRs []string
+	maxProbes int64
+	pool      netip.Prefix
+	used      []netip.Prefix
+	netNum    int64
+	probes    int64
+	subnet    netip.Prefix
+}
+
+func (o *cidrSubnetHashOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, ValidateCidrSubnetHashParameters(p)...)
+}
+
+func (o *cidrSubnetHashOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	o.newBits = p.NewBits
+	if err := r.Into("newBits", p.NewBitsField, &o.newBits); err != nil {
+		return err
+	}
+	if len(o.newBits) == 0 {
+		return errors.Errorf("cidrFunc cidrsubnethash requires newbits for %s", r.Kind())
+	}
+
+	keyField := p.HashKeyField
+	if p.HashKey == "" && keyField == "" {
+		keyField = "metadata.uid"
+	}
+	if o.key, err = r.String("hashKey", p.HashKey, keyField); err != nil {
+		return err
+	}
+	if o.key == "" {
+		return errors.Errorf("cidrFunc cidrsubnethash requires a non-empty hashKey for %s", r.Kind())
+	}
+
+	o.usedCIDRs = p.UsedCIDRs
+	if err := r.Into("usedCIDRs", p.UsedCIDRsField, &o.usedCIDRs); err != nil {
+		return err
+	}
+	o.maxProbes, err = r.Int("maxProbes", p.MaxProbes, "")
+	return err
+}
+
+func (o *cidrSubnetHashOperation) Compute() error {
+	var err error
+	if o.pool, err = cidr.ParsePrefix(o.prefix); err != nil {
+		return err
+	}
+	if o.used, err = parsePrefixes(o.usedCIDRs); err != nil {
+		return err
+	}
+	o.netNum = cidr.HashNetNum(o.key, o.newBits[0])
+	o.subnet, o.probes, err = cidr.HashSubnet(o.pool, o.newBits[0], o.key, o.maxProbes, o.used...)
+	return err
+}
+
+func (o *cidrSubnetHashOperation) Render() (any, error) {
+	return o.subnet.String(), nil
+}
+
+func (o *cidrSubnetHashOperation) Intermediates() map[string]any {
+	return map[string]any{"pool": o.pool.String(), "newBits": o.newBits[0], "hashNetNum": o.netNum, "probes": o.probes}
+}
+
+// Allocations returns the subnet and the used CIDRs, which are allocated from
+// the pool as well.
+func (o *cidrSubnetHashOperation) Allocations() map[netip.Prefix][]netip.Prefix {
+	return map[netip.Prefix][]netip.Prefix{o.pool: append([]netip.Prefix{o.subnet}, o.used...)}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func init() {
+	operation.DefaultRegistry.MustRegister("cidrsubnetloop", func() operation.CidrOperation { return &cidrSubnetLoopOperation{} })
+}
+
+// ValidateCidrSubnetloopParameters validates the Parameters object
+// in the context of cidrsubnetloop
+func ValidateCidrSubnetloopParameters(p *v1beta1.Parameters) field.ErrorList {
+	path := field.NewPath("parameters")
+	var errs field.ErrorList
+
+	if p.NetNumCount > 0 && len(p.NetNumCountField) > 0 {
+		// only one of netnumcount or NetNumCountField
+		errStr := "cidrFunc cidrsubnetloop requires either one of netnumcount or netnumcountfield, "
+		errStr += "but only if nonetnumitems or netnumitemsfield have been specified"
+		errs = append(errs, field.Forbidden(path.Child("netNumCountField"), errStr))
+	}
+	if len(p.NetNumItems) > 0 && len(p.NetNumItemsField) > 0 {
+		// only one of netnumitems or netnumitemsfield
+		errStr := "cidrFunc cidrsubnetloop requires either one of netnumitems or netnumitemsfield, "
+		errStr += "but only if nonetnumcount or netnumcountfield have been specified"
+		errs = append(errs, field.Forbidden(path.Child("netNumItemsField"), errStr))
+	}
+
+	netNumCountSpecified := p.NetNumCount > 0 || len(p.NetNumCountField) > 0
+	netNumItemsSpecified := len(p.NetNumItems) > 0 || len(p.NetNumItemsField) > 0
+	if netNumCountSpecified && netNumItemsSpecified {
+		// only either netnumcount or items
+		errStr := "cidrFunc cidrsubnetloop requires either one of netnumitems or netnumitemsfield, "
+		errStr += "or mutually exclusive one of netnumcount or netnumcountfield, but not both counts and items"
+		errs = append(errs, field.Forbidden(path.Child("netNumItems"), errStr))
+	}
+	if len(p.NewBits) > 0 && len(p.NewBitsField) > 0 {
+		errs = append(errs, field.Forbidden(path.Child("newBitsField"), "cidrFunc cidrsubnetloop requires either one of newbits or newbitsfield"))
+	}
+	errs = append(errs, validateNewBits(path.Child("newBits"), p.NewBits, 0, addressBits(p.Prefix))...)
+	if p.Offset > 0 && len(p.OffsetField) > 0 {
+		errs = append(errs, field.Forbidden(path.Child("offsetField"), "cidrFunc cidrsubnetloop requires either one of offset or offsetfield"))
+	}
+	errs = append(errs, validateUsedCIDRs(p)...)
+	if len(p.ReservedNetNums) > 0 && p.ReservedNetNumsField != "" {
+		errs = append(errs, field.Forbidden(path.Child("reservedNetNumsField"), "specify only one of reservedNetNums or reservedNetNumsField to avoid ambiguous function input"))
+	}
+	for i, n := range p.ReservedNetNums {
+		if n < 0 {
+			errs = append(errs, field.Invalid(path.Child("reservedNetNums").Index(i), n, "reservedNetNums must not be negative"))
+		}
+	}
+
+	subnetItemsSpecified := len(p.SubnetItems) > 0 || len(p.SubnetItemsField) > 0
+	if len(p.SubnetItems) > 0 && len(p.SubnetItemsField) > 0 {
+		errs = append(errs, field.Forbidden(path.Child("subnetItemsField"), "specify only one of subnetItems or subnetItemsField to avoid ambiguous function input"))
+	}
+	if subnetItemsSpecified && (netNumCountSpecified || netNumItemsSpecified || len(p.NewBits) > 0 || len(p.NewBitsField) > 0) {
+		errs = append(errs, field.Forbidden(path.Child("subnetItems"), "cidrFunc cidrsubnetloop requires either subnetitems or newbits with netnumcount or netnumitems, but not both"))
+	}
+	if subnetItemsSpecified && (p.Offset > 0 || len(p.OffsetField) > 0 || len(p.ReservedNetNums) > 0 || len(p.ReservedNetNumsField) > 0) {
+		errs = append(errs, field.Forbidden(path.Child("subnetItems"), "cidrFunc cidrsubnetloop cannot skip netnums of subnetitems, use usedcidrs instead"))
+	}
+	errs = append(errs, validateSubnetItems(path.Child("subnetItems"), p.SubnetItems)...)
+
+	return errs
+}
+
+// validateSubnetItems validates that every subnet item has a unique name and
+// extends the prefix by 1 to 32 bits.
+func validateSubnetItems(path *field.Path, items []v1beta1.SubnetItem) field.ErrorList {
+	var errs field.ErrorList
+	names := make(map[string]bool, len(items))
+	for i, it := range items {
+		switch {
+		case it.Name == "":
+			errs = append(errs, field.Required(path.Index(i).Child("name"), "name is required"))
+		case names[it.Name]:
+			errs = append(errs, field.Duplicate(path.Index(i).Child("name"), it.Name))
+		}
+		names[it.Name] = true
+		if it.NewBits < 1 || it.NewBits > cidr.Bits32 {
+			errs = append(errs, field.Invalid(path.Index(i).Child("newBits"), it.NewBits, fmt.Sprintf("newBits must be between 1 and %d", cidr.Bits32)))
+		}
+	}
+	return errs
+}
+
+// cidrSubnetLoopOperation is a convenience wrapper around cidrsubnet that
+// loops over a range of items, e.g. AZs or subnets or takes a count for its
+// iterations. Reserved netNums and netNums of subnets that overlap used CIDRs
+// are skipped. Subnet items with their own newBits are laid out like
+// cidrsubnets does instead.
+type cidrSubnetLoopOperation struct {
+	prefix          string
+	newBits         []int
+	offset          int64
+	netNumCount     int64
+	names           []string
+	usedCIDRs       []string
+	reservedNetNums []int64
+	pool            netip.Prefix
+	used            []netip.Prefix
+	netNums         []int64
+	subnets         []netip.Prefix
+}
+
+func (o *cidrSubnetLoopOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, ValidateCidrSubnetloopParameters(p)...)
+}
+
+func (o *cidrSubnetLoopOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	if len(p.SubnetItems) > 0 || len(p.SubnetItemsField) > 0 {
+		if err := o.resolveSubnetItems(p, r); err != nil {
+			return err
+		}
+	} else if err := o.resolveNetNumItems(p, r); err != nil {
+		return err
+	}
+
+	o.usedCIDRs = p.UsedCIDRs
+	if err := r.Into("usedCIDRs", p.UsedCIDRsField, &o.usedCIDRs); err != nil {
+		return err
+	}
+	o.reservedNetNums = p.ReservedNetNums
+	if err := r.Into("reservedNetNums", p.ReservedNetNumsField, &o.reservedNetNums); err != nil {
+		return err
+	}
+	return nil
+}
+
+// resolveNetNumItems resolves the newBits and the number of networks from
+// the newBits, netNumItems and netNumCount parameters.
+func (o *cidrSubnetLoopOperation) resolveNetNumItems(p *v1beta1.Parameters, r *operation.Resolver) error {
+	o.newBits = p.NewBits
+	if err := r.Into("newBits", p.NewBitsField, &o.newBits); err != nil {
+		return err
+	}
+	if len(o.newBits) == 0 {
+		return errors.Errorf("cidrFunc cidrsubnetloop requires newbits for %s", r.Kind())
+	}
+	var err error
+	if o.offset, err = r.Int("offset", int64(p.Offset), p.OffsetField); err != nil {
+		return err
+	}
+
+	netNumItems := p.NetNumItems
+	if err := r.Into("netNumItems", p.NetNumItemsField, &netNumItems); err != nil {
+		return err
+	}
+
+	netNumCount := p.NetNumCount
+	if int64(len(netNumItems)) > netNumCount {
+		netNumCount = int64(len(netNumItems))
+	}
+	o.netNumCount, err = r.Int("netNumCount", netNumCount, p.NetNumCountField)
+	return err
+}
+
+// resolveSubnetItems resolves the names and newBits of the networks from the
+// subnetItems parameter.
+func (o *cidrSubnetLoopOperation) resolveSubnetItems(p *v1beta1.Parameters, r *operation.Resolver) error {
+	items := p.SubnetItems
+	if err := r.Into("subnetItems", p.SubnetItemsField, &items); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return errors.Errorf("cidrFunc cidrsubnetloop requires subnetitems for %s", r.Kind())
+	}
+	if errs := validateSubnetItems(field.NewPath("subnetItems"), items); len(errs) > 0 {
+		return errors.Wrapf(errs.ToAggregate(), "invalid subnetItems for %s", r.Kind())
+	}
+	o.names = make([]string, len(items))
+	o.newBits = make([]int, len(items))
+	for i, it := range items {
+		o.names[i] = it.Name
+		o.newBits[i] = it.NewBits
+	}
+	o.netNumCount = int64(len(items))
+	return nil
+}
+
+func (o *cidrSubnetLoopOperation) Compute() error {
+	var err error
+	if o.pool, err = cidr.ParsePrefix(o.prefix); err != nil {
+		return err
+	}
+	if o.used, err = parsePrefixes(o.usedCIDRs); err != nil {
+		return err
+	}
+	if o.names != nil {
+		o.subnets, err = cidr.SubnetsAround(o.pool, o.used, o.newBits...)
+		return err
+	}
+	for netNum := o.offset; int64(len(o.subnets)) < o.netNumCount; netNum++ {
+		if slices.Contains(o.reservedNetNums, netNum) {
+			continue
+		}
+		subnet, err := cidr.Subnet(o.pool, o.newBits[0], netNum)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(o.used, subnet.Overlaps) {
+			continue
+		}
+		o.netNums = append(o.netNums, netNum)
+		o.subnets = append(o.subnets, subnet)
+	}
+	return nil
+}
+
+// Render returns the subnets keyed by the name of their subnet item, or as a
+// list if they were computed from newBits.
+func (o *cidrSubnetLoopOperation) Render() (any, error) {
+	if o.names == nil {
+		return prefixStrings(o.subnets), nil
+	}
+	out := make(map[string]string, len(o.names))
+	for i, name := range o.names {
+		out[name] = o.subnets[i].String()
+	}
+	return out, nil
+}
+
+func (o *cidrSubnetLoopOperation) Intermediates() map[string]any {
+	return map[string]any{"pool": o.pool.String(), "netNums": o.netNums}
+}
+
+// Allocations returns the subnets and the used CIDRs, which are allocated from
+// the pool as well.
+func (o *cidrSubnetLoopOperation) Allocations() map[netip.Prefix][]netip.Prefix {
+	return map[netip.Prefix][]netip.Prefix{o.pool: append(slices.Clone(o.subnets), o.used...)}
+}
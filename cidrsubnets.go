@@ -1,76 +1,96 @@
 package main
 
 import (
-	"fmt"
-	"net"
+	"net/netip"
+	"slices"
 
-	"github.com/apparentlymart/go-cidr/cidr"
-	"github.com/pkg/errors"
-)
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
-const Bits32 = 32
-const Bits128 = 128
+	"github.com/crossplane/function-sdk-go/resource"
 
-func CidrSubnets(prefix string, newbits ...int) ([][]byte, error) {
-	_, network, err := net.ParseCIDR(prefix)
-	if err != nil {
-		return nil, err
-	}
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
 
-	startPrefixLen, _ := network.Mask.Size()
+func init() {
+	operation.DefaultRegistry.MustRegister("cidrsubnets", func() operation.CidrOperation { return &cidrSubnetsOperation{} })
+}
+
+// ValidateCidrSubnetsParameters validates the Parameters object
+// in the context of cidrsubnet
+func ValidateCidrSubnetsParameters(p *v1beta1.Parameters, oxr *resource.Composite) field.ErrorList {
+	path := field.NewPath("parameters")
+	var errs field.ErrorList
 
-	prefixLengthArgs := newbits
-	if len(prefixLengthArgs) == 0 {
-		return nil, nil
+	if len(p.NewBits) > 0 && len(p.NewBitsField) > 0 {
+		errs = append(errs, field.Forbidden(path.Child("newBitsField"), "cidrFunc cidrsubnets requires either one of newbits or newbitsfield"))
 	}
+	errs = append(errs, validateNewBits(path.Child("newBits"), p.NewBits, 1, cidr.Bits32)...)
+	errs = append(errs, validateUsedCIDRs(p)...)
 
-	var firstLength int
-	firstLength = newbits[0]
-	firstLength += startPrefixLen
+	if len(p.NewBitsField) > 0 && oxr != nil {
+		var newBits []int
+		if err := oxr.Resource.GetValueInto(p.NewBitsField, &newBits); err != nil {
+			errs = append(errs, field.Invalid(path.Child("newBitsField"), p.NewBitsField, "cannot get newbits at newbitsfield"))
+		}
+	}
 
-	retVals := make([][]byte, len(prefixLengthArgs))
+	return errs
+}
 
-	current, _ := cidr.PreviousSubnet(network, firstLength)
-	for i, lengthArg := range prefixLengthArgs {
-		var length int
-		length = lengthArg
+// cidrSubnetsOperation calculates a sequence of consecutive IP address ranges
+// within a particular CIDR prefix, routing around CIDRs that are already used.
+// https://developer.hashicorp.com/terraform/language/functions/cidrsubnets
+type cidrSubnetsOperation struct {
+	prefix    string
+	newBits   []int
+	usedCIDRs []string
+	pool      netip.Prefix
+	used      []netip.Prefix
+	subnets   []netip.Prefix
+}
 
-		if length < 1 {
-			return nil, errors.New("must extend prefix by at least one bit")
-		}
-		// For portability with 32-bit systems where the subnet number
-		// will be a 32-bit int, we only allow extension of 32 bits in
-		// one call even if we're running on a 64-bit machine.
-		// (Of course, this is significant only for IPv6.)
-		if length > Bits32 {
-			return nil, errors.New("may not extend prefix by more than 32 bits")
-		}
-		length += startPrefixLen
-		if length > (len(network.IP) * 8) {
-			protocol := "IP"
-			switch len(network.IP) * 8 {
-			case Bits32:
-				protocol = "IPv4"
-			case Bits128:
-				protocol = "IPv6"
-			}
-			errTxt := fmt.Sprintf("would extend prefix to %d bits, which is too long for an %s address", length, protocol)
-			return nil, errors.New(errTxt)
-		}
+func (o *cidrSubnetsOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, ValidateCidrSubnetsParameters(p, r.Composite())...)
+}
 
-		next, rollover := cidr.NextSubnet(current, length)
-		if rollover || !network.Contains(next.IP) {
-			// If we run out of suffix bits in the base CIDR prefix then
-			// NextSubnet will start incrementing the prefix bits, which
-			// we don't allow because it would then allocate addresses
-			// outside of the caller's given prefix.
-			errTxt := fmt.Sprintf("not enough remaining address space for a subnet with a prefix of %d bits after %s", length, current.String())
-			return nil, errors.New(errTxt)
-		}
+func (o *cidrSubnetsOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	o.newBits = p.NewBits
+	if err := r.Into("newBits", p.NewBitsField, &o.newBits); err != nil {
+		return err
+	}
+	o.usedCIDRs = p.UsedCIDRs
+	return r.Into("usedCIDRs", p.UsedCIDRsField, &o.usedCIDRs)
+}
 
-		current = next
-		retVals[i] = []byte(current.String())
+func (o *cidrSubnetsOperation) Compute() error {
+	var err error
+	if o.pool, err = cidr.ParsePrefix(o.prefix); err != nil {
+		return err
+	}
+	if o.used, err = parsePrefixes(o.usedCIDRs); err != nil {
+		return err
 	}
+	o.subnets, err = cidr.SubnetsAround(o.pool, o.used, o.newBits...)
+	return err
+}
+
+func (o *cidrSubnetsOperation) Render() (any, error) {
+	return prefixStrings(o.subnets), nil
+}
+
+func (o *cidrSubnetsOperation) Intermediates() map[string]any {
+	return map[string]any{"pool": o.pool.String()}
+}
 
-	return retVals, nil
+// Allocations returns the subnets and the used CIDRs, which are allocated from
+// the pool as well.
+func (o *cidrSubnetsOperation) Allocations() map[netip.Prefix][]netip.Prefix {
+	return map[netip.Prefix][]netip.Prefix{o.pool: append(slices.Clone(o.subnets), o.used...)}
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/response"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+// reasonDebug is the reason of the result the debug output is attached as.
+const reasonDebug = "Debug"
+
+// DebugInfo describes how the Function resolved its inputs and what it
+// calculated from them. It is attached to the response if the debug parameter
+// is true.
+type DebugInfo struct {
+	// CidrFunc that was run.
+	CidrFunc string `json:"cidrFunc"`
+
+	// Parameters resolved by the cidrFunc, in the order they were resolved.
+	Parameters []operation.ResolvedParameter `json:"parameters"`
+
+	// Intermediates are the intermediate values of the calculation, if the
+	// cidrFunc reports any.
+	Intermediates map[string]any `json:"intermediates,omitempty"`
+
+	// OutputField the output was written to.
+	OutputField string `json:"outputField"`
+
+	// Output of the cidrFunc. Empty if the calculation failed.
+	Output any `json:"output,omitempty"`
+}
+
+// NewDebugInfo returns the DebugInfo of a run of the supplied operation.
+func NewDebugInfo(cidrFunc, outputField string, r *operation.Resolver, op operation.CidrOperation, output any) *DebugInfo {
+	info := &DebugInfo{
+		CidrFunc:    cidrFunc,
+		Parameters:  r.Resolved(),
+		OutputField: outputField,
+		Output:      output,
+	}
+	if d, ok := op.(operation.Debugger); ok {
+		info.Intermediates = d.Intermediates()
+	}
+	return info
+}
+
+// attachDebugInfo attaches the supplied DebugInfo to the response as a Normal
+// result, and to the pipeline context if the input requests it. It does
+// nothing unless the debug parameter is true.
+func attachDebugInfo(rsp *fnv1.RunFunctionResponse, in *v1beta1.Parameters, info *DebugInfo) error {
+	if !in.Debug {
+		return nil
+	}
+
+	b, err := json.Marshal(info)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal debug output")
+	}
+	response.Normal(rsp, string(b)).WithReason(reasonDebug)
+
+	if in.DebugContextKey == "" {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return errors.Wrap(err, "cannot unmarshal debug output")
+	}
+	sv, err := structpb.NewValue(v)
+	if err != nil {
+		return errors.Wrap(err, "cannot convert debug output")
+	}
+	response.SetContextKey(rsp, in.DebugContextKey, sv)
+	return nil
+}
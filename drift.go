@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/request"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/response"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+)
+
+// conditionCIDRsInSync is the type of the condition the Function sets on the
+// composite resource if drift checks are configured.
+const conditionCIDRsInSync = "CIDRsInSync"
+
+// Reasons of the CIDRsInSync condition and of drift warnings.
+const (
+	reasonInSync  = "InSync"
+	reasonDrifted = "Drifted"
+)
+
+// outputKey is the key the output of a cidrFunc is paved under to select
+// values from it by OutputPath.
+const outputKey = "output"
+
+// A Drift is a mismatch between a computed value and a field of an observed
+// composed resource.
+type Drift struct {
+	Check    v1beta1.DriftCheck
+	Computed any
+	Observed any
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("field %s of composed resource %s is %v, but %v was computed", d.Check.FieldPath, d.Check.ResourceName, d.Observed, d.Computed)
+}
+
+// validateDriftChecks validates the driftChecks parameter.
+func validateDriftChecks(checks []v1beta1.DriftCheck) field.ErrorList {
+	path := field.NewPath("parameters").Child("driftChecks")
+	var errs field.ErrorList
+	for i, c := range checks {
+		if c.ResourceName == "" {
+			errs = append(errs, field.Required(path.Index(i).Child("resourceName"), "resourceName is required for each drift check"))
+		}
+		if c.FieldPath == "" {
+			errs = append(errs, field.Required(path.Index(i).Child("fieldPath"), "fieldPath is required for each drift check"))
+		}
+		if _, err := fieldpath.Parse(outputPath(c.OutputPath)); err != nil {
+			errs = append(errs, field.Invalid(path.Index(i).Child("outputPath"), c.OutputPath, err.Error()))
+		}
+	}
+	return errs
+}
+
+// DetectDrift compares the supplied output of a cidrFunc with the fields of
+// the observed composed resources referenced by the supplied checks. Checks of
+// composed resources or fields that are not observed yet are skipped.
+func DetectDrift(req *fnv1.RunFunctionRequest, checks []v1beta1.DriftCheck, output any) ([]Drift, error) {
+	if len(checks) == 0 {
+		return nil, nil
+	}
+
+	ocds, err := request.GetObservedComposedResources(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get observed composed resources")
+	}
+
+	// Round-trip the output through JSON so it is made of the same types as
+	// the observed composed resources.
+	b, err := json.Marshal(map[string]any{outputKey: output})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal output")
+	}
+	out := map[string]any{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal output")
+	}
+	paved := fieldpath.Pave(out)
+
+	var drifts []Drift
+	for _, c := range checks {
+		computed, err := paved.GetValue(outputPath(c.OutputPath))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot get output path %q for drift check of composed resource %s", c.OutputPath, c.ResourceName)
+		}
+
+		ocd, ok := ocds[resource.Name(c.ResourceName)]
+		if !ok {
+			continue
+		}
+		observed, err := ocd.Resource.GetValue(c.FieldPath)
+		if fieldpath.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot get field %s of composed resource %s", c.FieldPath, c.ResourceName)
+		}
+
+		if !equalCIDRs(computed, observed) {
+			drifts = append(drifts, Drift{Check: c, Computed: computed, Observed: observed})
+		}
+	}
+	return drifts, nil
+}
+
+// reportDrift reports the supplied drifts as warnings and sets the
+// CIDRsInSync condition of the composite resource accordingly.
+func reportDrift(rsp *fnv1.RunFunctionResponse, drifts []Drift) {
+	if len(drifts) == 0 {
+		response.ConditionTrue(rsp, conditionCIDRsInSync, reasonInSync).
+			WithMessage("computed CIDRs match the observed composed resources").
+			TargetComposite()
+		return
+	}
+
+	msgs := make([]string, len(drifts))
+	for i, d := range drifts {
+		msgs[i] = d.String()
+		response.Warning(rsp, errors.New(msgs[i])).WithReason(reasonDrifted)
+	}
+	response.ConditionFalse(rsp, conditionCIDRsInSync, reasonDrifted).
+		WithMessage(strings.Join(msgs, "; ")).
+		TargetComposite()
+}
+
+// outputPath returns the field path of the supplied OutputPath within the
+// paved output.
+func outputPath(p string) string {
+	if p == "" || strings.HasPrefix(p, "[") {
+		return outputKey + p
+	}
+	return outputKey + "." + p
+}
+
+// equalCIDRs returns true if the supplied values are equal. Strings that are
+// CIDR prefixes are equal if they describe the same network.
+func equalCIDRs(a, b any) bool {
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if aok && bok {
+		ap, aerr := cidr.ParsePrefix(as)
+		bp, berr := cidr.ParsePrefix(bs)
+		if aerr == nil && berr == nil {
+			return ap == bp
+		}
+		return as == bs
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"net/netip"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func init() {
+	operation.DefaultRegistry.MustRegister("dualstackloop", func() operation.CidrOperation { return &dualStackLoopOperation{} })
+}
+
+// ValidateDualStackLoopParameters validates the Parameters object
+// in the context of dualstackloop
+func ValidateDualStackLoopParameters(p *v1beta1.Parameters) field.ErrorList {
+	path := field.NewPath("parameters")
+	var errs field.ErrorList
+
+	switch {
+	case len(p.NewBits) > 0 && len(p.NewBitsField) > 0:
+		errs = append(errs, field.Forbidden(path.Child("newBitsField"), "specify only one of newbits or newbitsfield to avoid ambiguous function input"))
+	case len(p.NewBits) == 0 && p.NewBitsField == "":
+		errs = append(errs, field.Required(path.Child("newBits"), "either newbits or newbitsfield function input is required"))
+	case p.NewBitsField == "" && len(p.NewBits) != 1:
+		errs = append(errs, field.Invalid(path.Child("newBits"), p.NewBits, "cidrFunc dualstackloop requires exactly 1 parameter in the array"))
+	}
+	errs = append(errs, validateNewBits(path.Child("newBits"), p.NewBits, 0, cidr.Bits32)...)
+
+	switch {
+	case p.IPv6NewBits > 0 && p.IPv6NewBitsField != "":
+		errs = append(errs, field.Forbidden(path.Child("ipv6NewBitsField"), "specify only one of ipv6NewBits or ipv6NewBitsField to avoid ambiguous function input"))
+	case p.IPv6NewBits == 0 && p.IPv6NewBitsField == "":
+		errs = append(errs, field.Required(path.Child("ipv6NewBits"), "either ipv6NewBits or ipv6NewBitsField function input is required"))
+	}
+	errs = append(errs, validateNewBits(path.Child("ipv6NewBits"), []int{p.IPv6NewBits}, 0, addressBits(p.IPv6Prefix))...)
+
+	if p.NetNumCount > 0 && p.NetNumCountField != "" {
+		errs = append(errs, field.Forbidden(path.Child("netNumCountField"), "specify only one of netNumCount or netNumCountField to avoid ambiguous function input"))
+	}
+	if len(p.NetNumItems) > 0 && p.NetNumItemsField != "" {
+		errs = append(errs, field.Forbidden(path.Child("netNumItemsField"), "specify only one of netNumItems or netNumItemsField to avoid ambiguous function input"))
+	}
+	if (p.NetNumCount > 0 || p.NetNumCountField != "") && (len(p.NetNumItems) > 0 || p.NetNumItemsField != "") {
+		errs = append(errs, field.Forbidden(path.Child("netNumItems"), "cidrFunc dualstackloop requires either netNumItems or netNumCount, but not both"))
+	}
+	items := make(map[string]bool, len(p.NetNumItems))
+	for i, item := range p.NetNumItems {
+		if items[item] {
+			errs = append(errs, field.Duplicate(path.Child("netNumItems").Index(i), item))
+		}
+		items[item] = true
+	}
+	if p.Offset > 0 && p.OffsetField != "" {
+		errs = append(errs, field.Forbidden(path.Child("offsetField"), "specify only one of offset or offsetField to avoid ambiguous function input"))
+	}
+
+	return errs
+}
+
+// dualStackLoopOperation pairs the IPv4 and IPv6 subnets with the same netNum
+// for a range of items, e.g. the AZs of a dual-stack VPC.
+type dualStackLoopOperation struct {
+	prefix      string
+	newBits     []int
+	ipv6Prefix  string
+	ipv6NewBits int64
+	offset      int64
+	netNumCount int64
+	items       []string
+	pool        netip.Prefix
+	ipv6Pool    netip.Prefix
+	netNums     []int64
+	subnets     []netip.Prefix
+	ipv6Subnets []netip.Prefix
+}
+
+func (o *dualStackLoopOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	errs = append(errs, validateNamedPrefix("ipv6Prefix", p.IPv6Prefix, p.IPv6PrefixField, r.Composite(), r.Request())...)
+	return append(errs, ValidateDualStackLoopParameters(p)...)
+}
+
+func (o *dualStackLoopOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	o.newBits = p.NewBits
+	if err := r.Into("newBits", p.NewBitsField, &o.newBits); err != nil {
+		return err
+	}
+	if len(o.newBits) == 0 {
+		return errors.Errorf("cidrFunc dualstackloop requires newbits for %s", r.Kind())
+	}
+	if o.ipv6Prefix, err = r.NamedPrefix("ipv6Prefix", p.IPv6Prefix, p.IPv6PrefixField); err != nil {
+		return err
+	}
+	if o.ipv6NewBits, err = r.Int("ipv6NewBits", int64(p.IPv6NewBits), p.IPv6NewBitsField); err != nil {
+		return err
+	}
+	if o.offset, err = r.Int("offset", int64(p.Offset), p.OffsetField); err != nil {
+		return err
+	}
+
+	o.items = p.NetNumItems
+	if err := r.Into("netNumItems", p.NetNumItemsField, &o.items); err != nil {
+		return err
+	}
+
+	netNumCount := max(p.NetNumCount, int64(len(o.items)))
+	o.netNumCount, err = r.Int("netNumCount", netNumCount, p.NetNumCountField)
+	return err
+}
+
+// Compute checks that both prefixes have room for every subnet before it
+// calculates any of them.
+func (o *dualStackLoopOperation) Compute() error {
+	var err error
+	if o.pool, err = cidr.ParsePrefix(o.prefix); err != nil {
+		return err
+	}
+	if !o.pool.Addr().Is4() {
+		return &cidr.InvalidPrefixError{Prefix: o.prefix, Err: errors.New("must be an IPv4 prefix")}
+	}
+	if o.ipv6Pool, err = cidr.ParsePrefix(o.ipv6Prefix); err != nil {
+		return err
+	}
+	if !o.ipv6Pool.Addr().Is6() {
+		return &cidr.InvalidPrefixError{Prefix: o.ipv6Prefix, Err: errors.New("must be an IPv6 prefix")}
+	}
+	if o.netNumCount <= 0 {
+		return nil
+	}
+
+	last := o.offset + o.netNumCount - 1
+	if _, err := cidr.Subnet(o.pool, o.newBits[0], last); err != nil {
+		return errors.Wrapf(err, "not enough IPv4 capacity for %d dual-stack subnets", o.netNumCount)
+	}
+	if _, err := cidr.Subnet(o.ipv6Pool, int(o.ipv6NewBits), last); err != nil {
+		return errors.Wrapf(err, "not enough IPv6 capacity for %d dual-stack subnets", o.netNumCount)
+	}
+
+	for netNum := o.offset; netNum <= last; netNum++ {
+		subnet, _ := cidr.Subnet(o.pool, o.newBits[0], netNum)
+		ipv6Subnet, _ := cidr.Subnet(o.ipv6Pool, int(o.ipv6NewBits), netNum)
+		o.netNums = append(o.netNums, netNum)
+		o.subnets = append(o.subnets, subnet)
+		o.ipv6Subnets = append(o.ipv6Subnets, ipv6Subnet)
+	}
+	return nil
+}
+
+// Render returns objects holding the ipv4 and ipv6 subnet of each network,
+// keyed by item if netNumItems are given and as a list otherwise.
+func (o *dualStackLoopOperation) Render() (any, error) {
+	if len(o.items) == 0 {
+		pairs := make([]any, len(o.subnets))
+		for i := range o.subnets {
+			pairs[i] = o.pair(i)
+		}
+		return pairs, nil
+	}
+	pairs := make(map[string]any, len(o.items))
+	for i, item := range o.items {
+		pairs[item] = o.pair(i)
+	}
+	return pairs, nil
+}
+
+// pair returns the ipv4 and ipv6 subnet of the i'th network.
+func (o *dualStackLoopOperation) pair(i int) map[string]any {
+	return map[string]any{"ipv4": o.subnets[i].String(), "ipv6": o.ipv6Subnets[i].String()}
+}
+
+func (o *dualStackLoopOperation) Intermediates() map[string]any {
+	return map[string]any{"pool": o.pool.String(), "ipv6Pool": o.ipv6Pool.String(), "netNums": o.netNums}
+}
+
+func (o *dualStackLoopOperation) Allocations() map[netip.Prefix][]netip.Prefix {
+	return map[netip.Prefix][]netip.Prefix{o.pool: o.subnets, o.ipv6Pool: o.ipv6Subnets}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/netip"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func init() {
+	operation.DefaultRegistry.MustRegister("eksprefixdelegation", func() operation.CidrOperation { return &eksPrefixDelegationOperation{} })
+}
+
+const (
+	// eksDelegatedPrefixLength is the length of the IPv4 prefixes the Amazon
+	// VPC CNI assigns to a network interface in prefix delegation mode.
+	eksDelegatedPrefixLength = 28
+
+	// eksAddressesPerPrefix is the number of pod IPs of a delegated prefix.
+	eksAddressesPerPrefix = 1 << (cidr.Bits32 - eksDelegatedPrefixLength)
+
+	// eksHostNetworkPods is the number of pods of a node that use the host
+	// network, i.e. aws-node and kube-proxy.
+	eksHostNetworkPods = 2
+
+	// The max pods recommended for instance types with fewer and with at
+	// least eksLargeInstanceVCPUs vCPUs.
+	eksSmallInstanceMaxPods = 110
+	eksLargeInstanceMaxPods = 250
+	eksLargeInstanceVCPUs   = 30
+)
+
+// ValidateEKSPrefixDelegationParameters validates the Parameters object
+// in the context of eksprefixdelegation
+func ValidateEKSPrefixDelegationParameters(p *v1beta1.Parameters) field.ErrorList {
+	path := field.NewPath("parameters")
+	switch {
+	case len(p.EKSInstanceTypes) > 0 && p.EKSInstanceTypesField != "":
+		return field.ErrorList{field.Forbidden(path.Child("eksInstanceTypesField"), "specify only one of eksInstanceTypes or eksInstanceTypesField to avoid ambiguous function input")}
+	case len(p.EKSInstanceTypes) == 0 && p.EKSInstanceTypesField == "":
+		return field.ErrorList{field.Required(path.Child("eksInstanceTypes"), "either eksInstanceTypes or eksInstanceTypesField function input is required")}
+	}
+	return validateEKSInstanceTypes(path.Child("eksInstanceTypes"), p.EKSInstanceTypes)
+}
+
+// validateEKSInstanceTypes validates that every instance type has a unique
+// name and positive network limits.
+func validateEKSInstanceTypes(path *field.Path, types []v1beta1.EKSInstanceType) field.ErrorList {
+	var errs field.ErrorList
+	seen := make(map[string]bool, len(types))
+	for i, t := range types {
+		switch {
+		case t.Name == "":
+			errs = append(errs, field.Required(path.Index(i).Child("name"), "name is required"))
+		case seen[t.Name]:
+			errs = append(errs, field.Duplicate(path.Index(i).Child("name"), t.Name))
+		}
+		seen[t.Name] = true
+		if t.ENIs < 1 {
+			errs = append(errs, field.Invalid(path.Index(i).Child("enis"), t.ENIs, "enis must be at least 1"))
+		}
+		if t.IPv4AddressesPerENI < 1 {
+			errs = append(errs, field.Invalid(path.Index(i).Child("ipv4AddressesPerENI"), t.IPv4AddressesPerENI, "ipv4AddressesPerENI must be at least 1"))
+		}
+		if t.VCPUs < 1 {
+			errs = append(errs, field.Invalid(path.Index(i).Child("vCPUs"), t.VCPUs, "vCPUs must be at least 1"))
+		}
+	}
+	return errs
+}
+
+// eksPrefixDelegationOperation calculates how many /28 prefixes the Amazon VPC
+// CNI can delegate from a subnet and the max pods of each instance type in
+// prefix delegation mode.
+type eksPrefixDelegationOperation struct {
+	prefix      string
+	types       []v1beta1.EKSInstanceType
+	pool        netip.Prefix
+	prefixCount int64
+	maxPods     []int64
+}
+
+func (o *eksPrefixDelegationOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, ValidateEKSPrefixDelegationParameters(p)...)
+}
+
+func (o *eksPrefixDelegationOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	o.types = p.EKSInstanceTypes
+	if err := r.Into("eksInstanceTypes", p.EKSInstanceTypesField, &o.types); err != nil {
+		return err
+	}
+	if len(o.types) == 0 {
+		return errors.Errorf("cidrFunc eksprefixdelegation requires eksInstanceTypes for %s", r.Kind())
+	}
+	if errs := validateEKSInstanceTypes(field.NewPath("eksInstanceTypes"), o.types); len(errs) > 0 {
+		return errors.Wrapf(errs.ToAggregate(), "invalid eksInstanceTypes for %s", r.Kind())
+	}
+	return nil
+}
+
+// Compute calculates the max pods of each instance type the way the EKS
+// max-pods-calculator does with prefix delegation enabled: every secondary
+// IP slot of an ENI holds a /28 prefix, plus the pods using the host network,
+// capped at the number of pods recommended for the vCPUs of the instance.
+func (o *eksPrefixDelegationOperation) Compute() error {
+	var err error
+	if o.pool, err = cidr.ParsePrefix(o.prefix); err != nil {
+		return err
+	}
+	if !o.pool.Addr().Is4() {
+		return &cidr.InvalidPrefixError{Prefix: o.prefix, Err: errors.New("must be an IPv4 prefix")}
+	}
+	if o.pool.Bits() > eksDelegatedPrefixLength {
+		return &PrefixTooSmallError{Prefix: o.pool, Name: "delegated", Length: eksDelegatedPrefixLength}
+	}
+	o.prefixCount = int64(1) << (eksDelegatedPrefixLength - o.pool.Bits())
+
+	o.maxPods = make([]int64, len(o.types))
+	for i, t := range o.types {
+		limit := int64(eksSmallInstanceMaxPods)
+		if t.VCPUs >= eksLargeInstanceVCPUs {
+			limit = eksLargeInstanceMaxPods
+		}
+		o.maxPods[i] = min(t.ENIs*(t.IPv4AddressesPerENI-1)*eksAddressesPerPrefix+eksHostNetworkPods, limit)
+	}
+	return nil
+}
+
+// Render returns the number of /28 prefixes of the subnet and, per instance
+// type, the max pods and the number of prefixes a node needs to run them.
+func (o *eksPrefixDelegationOperation) Render() (any, error) {
+	types := make(map[string]any, len(o.types))
+	for i, t := range o.types {
+		pods := max(o.maxPods[i]-eksHostNetworkPods, 0)
+		types[t.Name] = map[string]any{
+			"maxPods":         o.maxPods[i],
+			"prefixesPerNode": (pods + eksAddressesPerPrefix - 1) / eksAddressesPerPrefix,
+		}
+	}
+	return map[string]any{
+		"prefixLength":  eksDelegatedPrefixLength,
+		"prefixCount":   o.prefixCount,
+		"instanceTypes": types,
+	}, nil
+}
+
+func (o *eksPrefixDelegationOperation) Intermediates() map[string]any {
+	return map[string]any{"pool": o.pool.String()}
+}
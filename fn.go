@@ -2,6 +2,11 @@ package main
 
 import (
 	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
@@ -11,6 +16,7 @@ import (
 	"github.com/crossplane/function-sdk-go/response"
 
 	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/operation"
 )
 
 // Function runs CIDR calculations and composes CIDR resources.
@@ -18,28 +24,74 @@ type Function struct {
 	fnv1.UnimplementedFunctionRunnerServiceServer
 
 	log logging.Logger
+
+	// registry holds the supported cidrFuncs. DefaultRegistry is used if it
+	// is nil.
+	registry *operation.Registry
+
+	// metrics records metrics about each run. Nothing is recorded if it is
+	// nil.
+	metrics *Metrics
+
+	// tracer traces each run. The tracer of the global TracerProvider is
+	// used if it is nil.
+	tracer trace.Tracer
+}
+
+// operations returns the registry of cidrFuncs supported by the Function.
+func (f *Function) operations() *operation.Registry {
+	if f.registry == nil {
+		return operation.DefaultRegistry
+	}
+	return f.registry
+}
+
+// tracing returns the tracer of the Function.
+func (f *Function) tracing() trace.Tracer {
+	if f.tracer == nil {
+		return otel.Tracer(tracerName)
+	}
+	return f.tracer
 }
 
 // RunFunction runs the Function.
-func (f *Function) RunFunction(_ context.Context, req *fnv1.RunFunctionRequest) (*fnv1.RunFunctionResponse, error) {
+func (f *Function) RunFunction(ctx context.Context, req *fnv1.RunFunctionRequest) (*fnv1.RunFunctionResponse, error) {
 	rsp := response.To(req, response.DefaultTTL)
 
+	ctx, span := f.tracing().Start(ctx, "RunFunction")
+	var cidrFunc string
+	defer func(start time.Time) {
+		setFailedCondition(rsp)
+		f.metrics.ObserveRun(f.operations(), cidrFunc, rsp, time.Since(start))
+		span.SetAttributes(attribute.String(attrCidrFunc, cidrFunc))
+		endSpan(span, fatalError(rsp))
+	}(time.Now())
+
+	_, decode := f.tracing().Start(ctx, "DecodeInput")
 	input := &v1beta1.Parameters{}
 	if err := request.GetInput(req, input); err != nil {
-		response.Fatal(rsp, errors.Wrap(err, "cannot get Function input"))
+		fatalInput(rsp, errors.Wrap(err, "cannot get Function input"))
+		endSpan(decode, err)
 		return rsp, nil
 	}
+	cidrFunc = input.CidrFunc
 
 	oxr, err := request.GetObservedCompositeResource(req)
 	if err != nil {
 		response.Fatal(rsp, errors.Wrapf(err, "cannot get observed composite resource from %T", req))
+		endSpan(decode, err)
 		return rsp, nil
 	}
+	endSpan(decode, nil)
+	span.SetAttributes(compositeAttributes(oxr)...)
 
-	if err := ValidateParameters(input, oxr, req); err != nil {
-		response.Fatal(rsp, errors.Wrap(err, "invalid Function input"))
+	_, validate := f.tracing().Start(ctx, "ValidateParameters")
+	if errs := validateParameters(f.operations(), input, oxr, req); len(errs) > 0 {
+		fatalInput(rsp, errors.Wrap(errs.ToAggregate(), "invalid Function input"))
+		endSpan(validate, errs.ToAggregate())
 		return rsp, nil
 	}
+	endSpan(validate, nil)
 
 	log := f.log.WithValues(
 		"oxr-version", oxr.Resource.GetAPIVersion(),
@@ -56,248 +108,69 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1.RunFunctionRequest)
 	dxr.Resource.SetAPIVersion(oxr.Resource.GetAPIVersion())
 	dxr.Resource.SetKind(oxr.Resource.GetKind())
 
-	cidrFunc := input.CidrFunc
-	if len(input.CidrFuncField) > 0 {
-		cidrFunc, err = oxr.Resource.GetString(input.CidrFuncField)
-		if err != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot get cidrFunc from field %s for %s", input.CidrFunc, oxr.Resource.GetKind()))
-			return rsp, nil
-		}
+	_, resolve := f.tracing().Start(ctx, "Resolve")
+	r := operation.NewResolver(oxr, req)
+	if cidrFunc, err = r.String("cidrFunc", input.CidrFunc, input.CidrFuncField); err != nil {
+		fatalInput(rsp, err)
+		endSpan(resolve, err)
+		return rsp, nil
 	}
 	log.Info("Running function", "cidrFunc", cidrFunc)
+	resolve.SetAttributes(attribute.String(attrCidrFunc, cidrFunc))
 
-	prefix := input.Prefix
-	if cidrFunc != "multiprefixloop" && len(input.PrefixField) > 0 {
-		prefix, err = GetPrefixField(input.PrefixField, oxr, req)
-		if err != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot get prefix from field %s for %s", input.PrefixField, oxr.Resource.GetKind()))
-			return rsp, nil
-		}
+	op, err := f.operations().New(cidrFunc)
+	if err != nil {
+		fatalInput(rsp, err)
+		endSpan(resolve, err)
+		return rsp, nil
 	}
 
 	field := input.OutputField
 	if field == "" {
-		field = "status.atFunction.cidr"
+		field = defaultOutputField
 	}
 
-	switch cidrFunc {
-	// cidrhost calculates the host CIDR from a prefix and a host number.
-	// https://developer.hashicorp.com/terraform/language/functions/cidrhost
-	case "cidrhost":
-		hostNum := int64(input.HostNum)
-		if len(input.HostNumField) > 0 {
-			hostNum, err = oxr.Resource.GetInteger(input.HostNumField)
-			if err != nil {
-				response.Fatal(rsp, errors.Wrapf(err, "cannot get hostnum from field %s for %s", input.HostNumField, oxr.Resource.GetKind()))
-				return rsp, nil
-			}
-		}
-		host, cidrHostErr := CidrHost(prefix, int(hostNum))
-		if cidrHostErr != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot calculate CIDR host number for %s", oxr.Resource.GetKind()))
-			return rsp, nil
-		}
-
-		err = dxr.Resource.SetString(field, host)
-		if err != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot set field %s to %s for %s", field, host, oxr.Resource.GetKind()))
-			return rsp, nil
-		}
-
-	// cidrnetmask calculates the netmask from a prefix.
-	// https://developer.hashicorp.com/terraform/language/functions/cidrnetmask
-	case "cidrnetmask":
-		netmask, cidrNetmaskErr := CidrNetmask(prefix)
-		if cidrNetmaskErr != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot calculate CIDR netmask for %s", oxr.Resource.GetKind()))
-			return rsp, nil
-		}
-
-		err = dxr.Resource.SetString(field, netmask)
-		if err != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot set field %s to %s for %s", field, netmask, oxr.Resource.GetKind()))
-			return rsp, nil
-		}
-
-	// cidrsubnet calculates a subnet CIDR from a prefix, a net number
-	// and a new bits.
-	// https://developer.hashicorp.com/terraform/language/functions/cidrsubnet
-	case "cidrsubnet":
-		var newBits []int
-		newBits = input.NewBits
-		if len(input.NewBitsField) > 0 {
-			err = oxr.Resource.GetValueInto(input.NewBitsField, &newBits)
-			if err != nil {
-				response.Fatal(rsp, errors.Wrapf(err, "cannot get newbits from field %s of %s", input.NewBitsField, oxr.Resource.GetKind()))
-				return rsp, nil
-			}
-		}
-		netNum := input.NetNum
-		if len(input.NetNumField) > 0 {
-			netNum, err = oxr.Resource.GetInteger(input.NetNumField)
-			if err != nil {
-				response.Fatal(rsp, errors.Wrapf(err, "cannot get netnum from field %s for %s", input.NetNumField, oxr.Resource.GetKind()))
-				return rsp, nil
-			}
-		}
-		cidr, cidrSubnetErr := CidrSubnet(prefix, newBits[0], netNum)
-		if cidrSubnetErr != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot calculate subnet CIDR for %s", oxr.Resource.GetKind()))
-			return rsp, nil
-		}
-
-		err = dxr.Resource.SetString(field, string(cidr))
-		if err != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot set field %s to %s for %s", field, string(cidr), oxr.Resource.GetKind()))
-			return rsp, nil
-		}
-
-	// cidrsubnets calculates a sequence of consecutive
-	// IP address ranges within a particular CIDR prefix.
-	// https://developer.hashicorp.com/terraform/language/functions/cidrsubnets
-	case "cidrsubnets":
-		var newBits []int
-		newBits = input.NewBits
-		if len(input.NewBitsField) > 0 {
-			err = oxr.Resource.GetValueInto(input.NewBitsField, &newBits)
-			if err != nil {
-				response.Fatal(rsp, errors.Wrapf(err, "cannot get newbits from field %s of %s", input.NewBitsField, oxr.Resource.GetKind()))
-				return rsp, nil
-			}
-		}
-		cidrs, err := CidrSubnets(prefix, newBits...)
-		if err != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot calculate Subnet CIDRs for %s", oxr.Resource.GetKind()))
-			return rsp, nil
-		}
-
-		var cidrSubnetsStringArray []string
-		for _, cidr := range cidrs {
-			cidrSubnetsStringArray = append(cidrSubnetsStringArray, string(cidr))
-		}
-
-		err = dxr.Resource.SetValue(field, cidrSubnetsStringArray)
-		if err != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot set field %s to %s for %s", field, cidrSubnetsStringArray, oxr.Resource.GetKind()))
-			return rsp, nil
-		}
-
-	// cidrsubnetloop is a convenience wrapper around cidrsubnet
-	// that loops over a range of items, e.g. AZs or subnets
-	// or takes a count for its iterations.
-	case "cidrsubnetloop":
-		var cidrSubnetLoopStringArray []string
-		var netNum int64
-		var netNumItems []string
-		var newBits []int
-
-		newBits = input.NewBits
-		if len(input.NewBitsField) > 0 {
-			err = oxr.Resource.GetValueInto(input.NewBitsField, &newBits)
-			if err != nil {
-				response.Fatal(rsp, errors.Wrapf(err, "cannot get newbits from field %s of %s", input.NewBitsField, oxr.Resource.GetKind()))
-				return rsp, nil
-			}
-		}
-		offset := int64(input.Offset)
-		if len(input.OffsetField) > 0 {
-			offset, err = oxr.Resource.GetInteger(input.OffsetField)
-			if err != nil {
-				response.Fatal(rsp, errors.Wrapf(err, "cannot get offset from field %s for %s", input.OffsetField, oxr.Resource.GetKind()))
-				return rsp, nil
-			}
-		}
-
-		netNumItems = input.NetNumItems
-		if len(input.NetNumItemsField) > 0 {
-			err = oxr.Resource.GetValueInto(input.NetNumItemsField, &netNumItems)
-			if err != nil {
-				response.Fatal(rsp, errors.Wrapf(err, "cannot get netnumitems from field %s for %s", input.NetNumItemsField, oxr.Resource.GetKind()))
-				return rsp, nil
-			}
-		}
-
-		netNumCount := input.NetNumCount
-		if int64(len(netNumItems)) > netNumCount {
-			netNumCount = int64(len(netNumItems))
-		}
+	if err := op.Resolve(input, r); err != nil {
+		fatalInput(rsp, err)
+		endSpan(resolve, err)
+		return rsp, nil
+	}
+	endSpan(resolve, nil)
 
-		if len(input.NetNumCountField) > 0 {
-			netNumCount, err = oxr.Resource.GetInteger(input.NetNumCountField)
-			if err != nil {
-				response.Fatal(rsp, errors.Wrapf(err, "cannot get netnumcount from field %s for %s", input.NetNumCountField, oxr.Resource.GetKind()))
-				return rsp, nil
-			}
-		}
+	_, compute := f.tracing().Start(ctx, "Compute", trace.WithAttributes(attribute.String(attrCidrFunc, cidrFunc)))
+	if err := op.Compute(); err != nil {
+		fatal(rsp, errors.Wrapf(err, "cannot calculate %s for %s", cidrFunc, oxr.Resource.GetKind()))
+		endSpan(compute, err)
+		f.debug(rsp, input, NewDebugInfo(cidrFunc, field, r, op, nil))
+		return rsp, nil
+	}
+	endSpan(compute, nil)
 
-		for netNum = 0; netNum < netNumCount; netNum++ {
-			cidr, cidrSubnetErr := CidrSubnet(prefix, newBits[0], netNum+offset)
-			if cidrSubnetErr != nil {
-				response.Fatal(rsp, errors.Wrapf(err, "cannot calculate subnet CIDR for %s", oxr.Resource.GetKind()))
-				return rsp, nil
-			}
-			cidrSubnetLoopStringArray = append(cidrSubnetLoopStringArray, string(cidr))
-		}
+	value, err := op.Render()
+	if err != nil {
+		response.Fatal(rsp, errors.Wrapf(err, "cannot render %s output for %s", cidrFunc, oxr.Resource.GetKind()))
+		return rsp, nil
+	}
 
-		err = dxr.Resource.SetValue(field, cidrSubnetLoopStringArray)
-		if err != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot set field %s to %s for %s", field, cidrSubnetLoopStringArray, oxr.Resource.GetKind()))
+	if input.Lock {
+		err := CheckLock(oxr, field, value)
+		var le *LockedError
+		switch {
+		case errors.As(err, &le) && input.LockPolicy != v1beta1.LockPolicyFatal:
+			response.Warning(rsp, err).WithReason(reasonLocked)
+			value = le.Locked
+		case errors.As(err, &le):
+			fatal(rsp, err)
 			return rsp, nil
-		}
-
-	// multiprefix is a convenience wrapper around cidrsubnets that	loops over a
-	// range of prefixes to create a list of subnets for each prefix.
-	case "multiprefixloop":
-		subnetsByCidr := make(map[string][]string)
-		multiPrefixes := input.MultiPrefix
-		if len(input.MultiPrefixField) > 0 {
-			err = oxr.Resource.GetValueInto(input.MultiPrefixField, &multiPrefixes)
-			if err != nil {
-				response.Fatal(rsp, errors.Wrapf(err, "cannot get multiprefix from field %s for %s", input.MultiPrefixField, oxr.Resource.GetKind()))
-				return rsp, nil
-			}
-		}
-
-		for _, multiPrefix := range multiPrefixes {
-			prefix := multiPrefix.Prefix
-			if len(prefix) == 0 {
-				continue
-			}
-
-			newBits := multiPrefix.NewBits
-			if len(newBits) == 0 {
-				continue
-			}
-
-			if multiPrefix.Offset > 0 {
-				newBits = append([]int{multiPrefix.Offset}, newBits...)
-			}
-
-			cidrs, err := CidrSubnets(prefix, newBits...)
-			if err != nil {
-				response.Fatal(rsp, errors.Wrapf(err, "cannot calculate Subnet CIDRs for %s", oxr.Resource.GetKind()))
-				return rsp, nil
-			}
-
-			var cidrSubnetsStringArray []string
-			for _, cidr := range cidrs {
-				cidrSubnetsStringArray = append(cidrSubnetsStringArray, string(cidr))
-			}
-
-			subnetsByCidr[prefix] = cidrSubnetsStringArray
-			if multiPrefix.Offset > 0 {
-				subnetsByCidr[prefix] = cidrSubnetsStringArray[1:]
-			}
-		}
-
-		err = dxr.Resource.SetValue(field, subnetsByCidr)
-		if err != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot set field %s to %s for %s", field, subnetsByCidr, oxr.Resource.GetKind()))
+		case err != nil:
+			response.Fatal(rsp, err)
 			return rsp, nil
 		}
+	}
 
-	default:
-		log.Info("internal error: sub function not supported: ", "cidrFunc", input.CidrFunc)
+	if err := dxr.Resource.SetValue(field, value); err != nil {
+		response.Fatal(rsp, errors.Wrapf(err, "cannot set field %s to %v for %s", field, value, oxr.Resource.GetKind()))
+		return rsp, nil
 	}
 
 	if err := response.SetDesiredCompositeResource(rsp, dxr); err != nil {
@@ -305,5 +178,24 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1.RunFunctionRequest)
 		return rsp, nil
 	}
 
+	setAllocatedCondition(rsp, cidrFunc, field)
+	if len(input.DriftChecks) > 0 {
+		drifts, err := DetectDrift(req, input.DriftChecks, value)
+		if err != nil {
+			response.Warning(rsp, errors.Wrap(err, "cannot detect drift"))
+		} else {
+			reportDrift(rsp, drifts)
+		}
+	}
+	f.metrics.ObserveOutput(cidrFunc, op, value)
+	f.debug(rsp, input, NewDebugInfo(cidrFunc, field, r, op, value))
 	return rsp, nil
 }
+
+// debug attaches the supplied DebugInfo to the response if the input requests
+// it. Failing to do so is reported as a warning.
+func (f *Function) debug(rsp *fnv1.RunFunctionResponse, in *v1beta1.Parameters, info *DebugInfo) {
+	if err := attachDebugInfo(rsp, in, info); err != nil {
+		response.Warning(rsp, err)
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -63,11 +65,8 @@ func TestRunFunction(t *testing.T) {
 							Resource: resource.MustStructJSON(`{"apiVersion":"","kind":"","status": {"atFunction": {"cidr": "127.0.0.111"}}}`),
 						},
 					},
-					Meta: &fnv1.ResponseMeta{
-						Ttl: &durationpb.Duration{
-							Seconds: 60,
-						},
-					},
+					Conditions: allocated("cidrhost", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
 				},
 				err: nil,
 			},
@@ -119,11 +118,8 @@ func TestRunFunction(t *testing.T) {
 							Resource: resource.MustStructJSON(`{"apiVersion":"","kind":"","status": {"atFunction": {"cidr": "127.0.0.3/32"}}}`),
 						},
 					},
-					Meta: &fnv1.ResponseMeta{
-						Ttl: &durationpb.Duration{
-							Seconds: 60,
-						},
-					},
+					Conditions: allocated("cidrsubnet", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
 				},
 				err: nil,
 			},
@@ -157,11 +153,8 @@ func TestRunFunction(t *testing.T) {
 							Resource: resource.MustStructJSON(`{"apiVersion":"","kind":"","status": {"atFunction": {"cidr": "255.255.255.0"}}}`),
 						},
 					},
-					Meta: &fnv1.ResponseMeta{
-						Ttl: &durationpb.Duration{
-							Seconds: 60,
-						},
-					},
+					Conditions: allocated("cidrnetmask", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
 				},
 				err: nil,
 			},
@@ -223,11 +216,8 @@ func TestRunFunction(t *testing.T) {
 							Resource: resource.MustStructJSON(`{"apiVersion":"","kind":"","status": {"atFunction": {"cidr": ["127.0.0.0/32", "127.0.0.16/28", "127.0.0.64/26"]}}}`),
 						},
 					},
-					Meta: &fnv1.ResponseMeta{
-						Ttl: &durationpb.Duration{
-							Seconds: 60,
-						},
-					},
+					Conditions: allocated("cidrsubnets", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
 				},
 				err: nil,
 			},
@@ -284,11 +274,8 @@ func TestRunFunction(t *testing.T) {
 							Resource: resource.MustStructJSON(`{"apiVersion":"","kind":"","status": {"atFunction": {"cidr": ["10.0.0.48/32", "10.0.0.49/32", "10.0.0.50/32"]}}}`),
 						},
 					},
-					Meta: &fnv1.ResponseMeta{
-						Ttl: &durationpb.Duration{
-							Seconds: 60,
-						},
-					},
+					Conditions: allocated("cidrsubnetloop", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
 				},
 				err: nil,
 			},
@@ -399,11 +386,8 @@ func TestRunFunction(t *testing.T) {
 								`"10.12.0.0/24": ["10.12.0.0/28", "10.12.0.16/28", "10.12.0.32/28"]}}}}`),
 						},
 					},
-					Meta: &fnv1.ResponseMeta{
-						Ttl: &durationpb.Duration{
-							Seconds: 60,
-						},
-					},
+					Conditions: allocated("multiprefixloop", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
 				},
 				err: nil,
 			},
@@ -414,8 +398,8 @@ func TestRunFunction(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				req: &fnv1.RunFunctionRequest{
-					Context: &structpb.Struct {
-						Fields: map[string]*structpb.Value {
+					Context: &structpb.Struct{
+						Fields: map[string]*structpb.Value{
 							"apiextensions.crossplane.io/extra-resources": structpb.NewStructValue(resource.MustStructJSON(`{
 									"XCluster": [
         							    {
@@ -476,8 +460,8 @@ func TestRunFunction(t *testing.T) {
 							Resource: resource.MustStructJSON(`{"apiVersion":"","kind":"","status": {"atFunction": {"cidr": {"partitions": ["10.0.0.0/21", "10.0.8.0/21"]}}}}`),
 						},
 					},
-					Context: &structpb.Struct {
-						Fields: map[string]*structpb.Value {
+					Context: &structpb.Struct{
+						Fields: map[string]*structpb.Value{
 							"apiextensions.crossplane.io/extra-resources": structpb.NewStructValue(resource.MustStructJSON(`{
 									"XCluster": [
         							    {
@@ -492,11 +476,8 @@ func TestRunFunction(t *testing.T) {
 							}`)),
 						},
 					},
-					Meta: &fnv1.ResponseMeta{
-						Ttl: &durationpb.Duration{
-							Seconds: 60,
-						},
-					},
+					Conditions: allocated("cidrsubnets", "status.atFunction.cidr.partitions"),
+					Meta:       responseMeta(),
 				},
 				err: nil,
 			},
@@ -581,30 +562,1471 @@ func TestRunFunction(t *testing.T) {
 							}`),
 						},
 					},
-					Meta: &fnv1.ResponseMeta{
-						Ttl: &durationpb.Duration{
-							Seconds: 60,
+					Conditions: allocated("cidrsubnets", "status.atFunction.cidr.private.subnets"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+
+		"multi-prefix-loop-invalid": {
+			reason: "should report every invalid multiPrefix entry in a single fatal result",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "multiprefixloop",
+						"multiPrefix": [
+							{"prefix": "10.10.0.0/24", "newBits": [8]},
+							{"prefix": "10.12.0.0", "newBits": [4]},
+							{"prefix": "10.14.0.0/24", "newBits": [4, 0]}
+						]
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message: "invalid Function input: [parameters.multiPrefix[1].prefix: Invalid value: \"10.12.0.0\": invalid CIDR prefix address, " +
+								"parameters.multiPrefix[2].newBits[1]: Invalid value: 0: newBits must be between 1 and 32]",
+							Reason: ptr("InvalidInput"),
+							Target: fnv1.Target_TARGET_COMPOSITE.Enum(),
 						},
 					},
+					Conditions: notAllocated("InvalidInput", "invalid Function input: [parameters.multiPrefix[1].prefix: Invalid value: \"10.12.0.0\": invalid CIDR prefix address, parameters.multiPrefix[2].newBits[1]: Invalid value: 0: newBits must be between 1 and 32]"),
+					Meta:       responseMeta(),
 				},
 				err: nil,
 			},
 		},
 
-	}
+		"multi-prefix-loop-named": {
+			reason: "should key the subnets by the name of their entry and by their own names",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "multiprefixloop",
+						"multiPrefix": [
+							{"name": "vpc-a", "prefix": "10.0.0.0/16", "newBits": [4, 4], "names": ["public", "private"]},
+							{"prefix": "10.1.0.0/16", "newBits": [8]}
+						]
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": {
+								"vpc-a": {"public": "10.0.0.0/20", "private": "10.0.16.0/20"},
+								"10.1.0.0/16": ["10.1.0.0/24"]
+							}}}}`),
+						},
+					},
+					Conditions: allocated("multiprefixloop", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
 
-	for name, tc := range cases {
-		t.Run(name, func(t *testing.T) {
-			f := &Function{log: logging.NewNopLogger()}
-			rsp, err := f.RunFunction(tc.args.ctx, tc.args.req)
+		"multi-prefix-loop-shared-prefix": {
+			reason: "should allocate subnets from a prefix shared by entries with different names",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "multiprefixloop",
+						"multiPrefix": [
+							{"name": "public", "prefix": "10.0.0.0/16", "newBits": [4]},
+							{"name": "private", "prefix": "10.0.0.0/16", "offset": 4, "newBits": [4]}
+						]
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"","kind":"","status": {"atFunction": {"cidr": {
+								"public": ["10.0.0.0/20"],
+								"private": ["10.0.16.0/20"]
+							}}}}`),
+						},
+					},
+					Conditions: allocated("multiprefixloop", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
 
-			if diff := cmp.Diff(tc.want.rsp, rsp, protocmp.Transform()); diff != "" {
-				t.Errorf("%s\nf.RunFunction(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
-			}
+		"multi-prefix-loop-duplicate-names": {
+			reason: "should reject entries and subnets that would share a key in the output",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "multiprefixloop",
+						"multiPrefix": [
+							{"name": "vpc", "prefix": "10.0.0.0/16", "newBits": [4, 4], "names": ["app", "app"]},
+							{"name": "vpc", "prefix": "10.1.0.0/16", "newBits": [8]}
+						]
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "invalid Function input: [parameters.multiPrefix[0].names[1]: Duplicate value: \"app\", parameters.multiPrefix[1].name: Duplicate value: \"vpc\"]",
+							Reason:   ptr("InvalidInput"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("InvalidInput", "invalid Function input: [parameters.multiPrefix[0].names[1]: Duplicate value: \"app\", parameters.multiPrefix[1].name: Duplicate value: \"vpc\"]"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
 
-			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
-				t.Errorf("%s\nf.RunFunction(...): -want err, +got err:\n%s", tc.reason, diff)
-			}
-		})
+		"cidr-subnets-exhausted": {
+			reason: "should report why the prefix has no room left for the requested subnets",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnets",
+						"prefix": "10.0.0.0/24",
+						"newBits": [1, 1, 1]
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "cannot calculate cidrsubnets for : not enough remaining address space in 10.0.0.0/24 for a subnet with a prefix of 25 bits after 10.0.0.128/25",
+							Reason:   ptr("PoolExhausted"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("PoolExhausted", "cannot calculate cidrsubnets for : not enough remaining address space in 10.0.0.0/24 for a subnet with a prefix of 25 bits after 10.0.0.128/25"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+
+		"cidr-subnet-netnum-out-of-range": {
+			reason: "should report the netnum that does not fit into newBits",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnet",
+						"prefix": "10.0.0.0/16",
+						"newBits": [2],
+						"netNum": 4
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "cannot calculate cidrsubnet for : netnum 4 is out of range for prefix 10.0.0.0/16 extended by 2 bits",
+							Reason:   ptr("NetNumOutOfRange"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("NetNumOutOfRange", "cannot calculate cidrsubnet for : netnum 4 is out of range for prefix 10.0.0.0/16 extended by 2 bits"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+
+		"unsupported-cidr-func-field": {
+			reason: "should fail and list the supported functions when cidrFuncField resolves to an unknown function",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFuncField": "spec.cidrFunc",
+						"prefix": "10.0.0.0/16"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","spec":{"cidrFunc":"cidrsplit"}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "invalid Function input: parameters.cidrFunc: Unsupported value: \"cidrsplit\": supported values: " + supportedValues(),
+							Reason:   ptr("InvalidInput"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("InvalidInput", "invalid Function input: parameters.cidrFunc: Unsupported value: \"cidrsplit\": supported values: "+supportedValues()),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnet-ipv6-large-newbits": {
+			reason: "should extend an IPv6 prefix by more than 32 bits",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnet",
+						"prefix": "fd00::/24",
+						"newBits": [40],
+						"netNum": 1
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"","kind":"","status": {"atFunction": {"cidr": "fd00:0:0:1::/64"}}}`),
+						},
+					},
+					Conditions: allocated("cidrsubnet", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnet-loop-debug": {
+			reason: "should attach the resolved parameters and intermediate values as a result and context key",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnetloop",
+						"prefixField": "spec.cidrBlock",
+						"newBits": [8],
+						"netNumItemsField": "spec.azs",
+						"offset": 1,
+						"debug": true,
+						"debugContextKey": "cidr-debug"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","spec":{"cidrBlock":"10.0.0.0/16","azs":["a","b"]}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": ["10.0.1.0/24", "10.0.2.0/24"]}}}`),
+						},
+					},
+					Context: resource.MustStructJSON(`{"cidr-debug": {"cidrFunc":"cidrsubnetloop","parameters":[{"name":"cidrFunc","value":"cidrsubnetloop"},{"name":"prefix","field":"spec.cidrBlock","value":"10.0.0.0/16"},{"name":"newBits","value":[8]},{"name":"offset","value":1},{"name":"netNumItems","field":"spec.azs","value":["a","b"]},{"name":"netNumCount","value":2},{"name":"usedCIDRs","value":null},{"name":"reservedNetNums","value":null}],"intermediates":{"netNums":[1,2],"pool":"10.0.0.0/16"},"outputField":"status.atFunction.cidr","output":["10.0.1.0/24","10.0.2.0/24"]}}`),
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `{"cidrFunc":"cidrsubnetloop","parameters":[{"name":"cidrFunc","value":"cidrsubnetloop"},{"name":"prefix","field":"spec.cidrBlock","value":"10.0.0.0/16"},{"name":"newBits","value":[8]},{"name":"offset","value":1},{"name":"netNumItems","field":"spec.azs","value":["a","b"]},{"name":"netNumCount","value":2},{"name":"usedCIDRs","value":null},{"name":"reservedNetNums","value":null}],"intermediates":{"netNums":[1,2],"pool":"10.0.0.0/16"},"outputField":"status.atFunction.cidr","output":["10.0.1.0/24","10.0.2.0/24"]}`,
+							Reason:   ptr("Debug"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: allocated("cidrsubnetloop", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnet-loop-reserved": {
+			reason: "should skip reserved netNums and netNums of used CIDRs",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnetloop",
+						"prefix": "10.0.0.0/16",
+						"newBits": [8],
+						"netNumCount": 3,
+						"reservedNetNums": [1],
+						"usedCIDRsField": "spec.legacySubnets"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","spec":{"legacySubnets":["10.0.3.0/24"]}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": ["10.0.0.0/24", "10.0.2.0/24", "10.0.4.0/24"]}}}`),
+						},
+					},
+					Conditions: allocated("cidrsubnetloop", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnets-used": {
+			reason: "should route the subnets around used CIDRs",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnets",
+						"prefix": "10.0.0.0/16",
+						"newBits": [4, 4],
+						"usedCIDRs": ["10.0.16.0/21"]
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": ["10.0.0.0/20", "10.0.32.0/20"]}}}`),
+						},
+					},
+					Conditions: allocated("cidrsubnets", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnet-loop-newbits-list": {
+			reason: "should use the first newBits value for every item",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnetloop",
+						"prefix": "10.0.0.0/16",
+						"newBits": [8, 8],
+						"netNumItems": ["a", "b", "c"],
+						"offset": 2
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": ["10.0.2.0/24", "10.0.3.0/24", "10.0.4.0/24"]}}}`),
+						},
+					},
+					Conditions: allocated("cidrsubnetloop", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnet-loop-subnet-items": {
+			reason: "should lay out a subnet per subnet item read from a field",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnetloop",
+						"prefix": "10.0.0.0/16",
+						"subnetItemsField": "spec.zones"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","spec":{"zones":[{"name":"a","newBits":8},{"name":"b","newBits":4}]}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": {"a": "10.0.0.0/24", "b": "10.0.16.0/20"}}}}`),
+						},
+					},
+					Conditions: allocated("cidrsubnetloop", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnet-loop-subnet-items-offset": {
+			reason: "should not skip netNums of subnet items",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnetloop",
+						"prefix": "10.0.0.0/16",
+						"subnetItems": [{"name": "a", "newBits": 8}, {"name": "a", "newBits": 4}],
+						"offset": 2
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "invalid Function input: [parameters.subnetItems: Forbidden: cidrFunc cidrsubnetloop cannot skip netnums of subnetitems, use usedcidrs instead, parameters.subnetItems[1].name: Duplicate value: \"a\"]",
+							Reason:   ptr("InvalidInput"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("InvalidInput", "invalid Function input: [parameters.subnetItems: Forbidden: cidrFunc cidrsubnetloop cannot skip netnums of subnetitems, use usedcidrs instead, parameters.subnetItems[1].name: Duplicate value: \"a\"]"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnets-drift": {
+			reason: "should warn about composed resources whose CIDRs differ from the computed ones",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnets",
+						"prefix": "10.0.0.0/16",
+						"newBits": [8, 8],
+						"driftChecks": [
+							{"resourceName": "subnet-a", "fieldPath": "spec.forProvider.cidrBlock", "outputPath": "[0]"},
+							{"resourceName": "subnet-b", "fieldPath": "spec.forProvider.cidrBlock", "outputPath": "[1]"},
+							{"resourceName": "subnet-c", "fieldPath": "spec.forProvider.cidrBlock", "outputPath": "[1]"}
+						]
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork"}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"subnet-a": {
+								Resource: resource.MustStructJSON(`{"apiVersion":"ec2.aws.upbound.io/v1beta1","kind":"Subnet","spec":{"forProvider":{"cidrBlock":"10.0.0.0/24"}}}`),
+							},
+							"subnet-b": {
+								Resource: resource.MustStructJSON(`{"apiVersion":"ec2.aws.upbound.io/v1beta1","kind":"Subnet","spec":{"forProvider":{"cidrBlock":"10.0.9.0/24"}}}`),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": ["10.0.0.0/24", "10.0.1.0/24"]}}}`),
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  "field spec.forProvider.cidrBlock of composed resource subnet-b is 10.0.9.0/24, but 10.0.1.0/24 was computed",
+							Reason:   ptr("Drifted"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: append(allocated("cidrsubnets", "status.atFunction.cidr"), &fnv1.Condition{
+						Type:    "CIDRsInSync",
+						Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+						Reason:  "Drifted",
+						Message: ptr("field spec.forProvider.cidrBlock of composed resource subnet-b is 10.0.9.0/24, but 10.0.1.0/24 was computed"),
+						Target:  fnv1.Target_TARGET_COMPOSITE.Enum(),
+					}),
+					Meta: responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnet-in-sync": {
+			reason: "should report composed resources whose CIDRs match the computed ones as in sync",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnet",
+						"prefix": "10.0.0.0/16",
+						"newBits": [8],
+						"netNum": 3,
+						"driftChecks": [
+							{"resourceName": "subnet", "fieldPath": "spec.forProvider.cidrBlock"}
+						]
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork"}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"subnet": {
+								Resource: resource.MustStructJSON(`{"apiVersion":"ec2.aws.upbound.io/v1beta1","kind":"Subnet","spec":{"forProvider":{"cidrBlock":"10.0.3.0/24"}}}`),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": "10.0.3.0/24"}}}`),
+						},
+					},
+					Conditions: append(allocated("cidrsubnet", "status.atFunction.cidr"), &fnv1.Condition{
+						Type:    "CIDRsInSync",
+						Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+						Reason:  "InSync",
+						Message: ptr("computed CIDRs match the observed composed resources"),
+						Target:  fnv1.Target_TARGET_COMPOSITE.Enum(),
+					}),
+					Meta: responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnet-locked": {
+			reason: "should keep the locked output and warn if the prefix changed",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnet",
+						"prefixField": "spec.cidrBlock",
+						"newBits": [8],
+						"netNum": 0,
+						"lock": true
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","spec":{"cidrBlock":"10.1.0.0/16"},"status":{"atFunction":{"cidr":"10.0.0.0/24"}}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": "10.0.0.0/24"}}}`),
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  "outputField status.atFunction.cidr is locked to 10.0.0.0/24, refusing to change it to 10.1.0.0/24",
+							Reason:   ptr("Locked"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: allocated("cidrsubnet", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnet-locked-fatal": {
+			reason: "should fail if the prefix changed and the lock policy is Fatal",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnet",
+						"prefixField": "spec.cidrBlock",
+						"newBits": [8],
+						"netNum": 0,
+						"lock": true,
+						"lockPolicy": "Fatal"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","spec":{"cidrBlock":"10.1.0.0/16"},"status":{"atFunction":{"cidr":"10.0.0.0/24"}}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "outputField status.atFunction.cidr is locked to 10.0.0.0/24, refusing to change it to 10.1.0.0/24",
+							Reason:   ptr("Locked"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("Locked", "outputField status.atFunction.cidr is locked to 10.0.0.0/24, refusing to change it to 10.1.0.0/24"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnet-hash": {
+			reason: "should hash the UID of the XR into a subnet, skipping used subnets",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnethash",
+						"prefix": "10.0.0.0/8",
+						"newBits": [16],
+						"usedCIDRsField": "spec.usedCIDRs"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","metadata":{"uid":"uid-1"},"spec":{"usedCIDRs":["10.74.73.0/24"]}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": "10.74.74.0/24"}}}`),
+						},
+					},
+					Conditions: allocated("cidrsubnethash", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-subnet-hash-exhausted": {
+			reason: "should fail if every probed subnet is used",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrsubnethash",
+						"prefix": "10.0.0.0/8",
+						"newBits": [16],
+						"hashKey": "uid-1",
+						"usedCIDRs": ["10.74.73.0/24"],
+						"maxProbes": 1
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "cannot calculate cidrsubnethash for XNetwork: no free subnet with a prefix of 24 bits in 10.0.0.0/8 after 1 probes",
+							Reason:   ptr("PoolExhausted"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("PoolExhausted", "cannot calculate cidrsubnethash for XNetwork: no free subnet with a prefix of 24 bits in 10.0.0.0/8 after 1 probes"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"ula-prefix": {
+			reason: "should derive a ULA prefix from the UID of the XR",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "ulaprefix"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","metadata":{"uid":"uid-1"}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": "fd31:d269:53cb::/48"}}}`),
+						},
+					},
+					Conditions: allocated("ulaprefix", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"ula-prefix-subnets": {
+			reason: "should derive a ULA prefix from a seed field and calculate subnets of it",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "ulaprefix",
+						"seedField": "spec.environment",
+						"newBits": [16],
+						"netNumCount": 2
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","spec":{"environment":"prod"}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": {"prefix": "fd54:a27:5ac9::/48", "subnets": ["fd54:a27:5ac9::/64", "fd54:a27:5ac9:1::/64"]}}}}`),
+						},
+					},
+					Conditions: allocated("ulaprefix", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"dual-stack-loop": {
+			reason: "should pair the IPv4 and IPv6 subnet of each item",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "dualstackloop",
+						"prefix": "10.0.0.0/16",
+						"newBits": [8],
+						"ipv6PrefixField": "status.ipv6CidrBlock",
+						"ipv6NewBits": 8,
+						"netNumItems": ["a", "b"]
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status":{"ipv6CidrBlock":"2600:1f18:abcd:1200::/56"}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": {"a": {"ipv4": "10.0.0.0/24", "ipv6": "2600:1f18:abcd:1200::/64"}, "b": {"ipv4": "10.0.1.0/24", "ipv6": "2600:1f18:abcd:1201::/64"}}}}}`),
+						},
+					},
+					Conditions: allocated("dualstackloop", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"dual-stack-loop-debug": {
+			reason: "should extend the IPv6 prefix by more than 32 bits and report the netNums of the subnets",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "dualstackloop",
+						"prefix": "10.0.0.0/16",
+						"newBits": [8],
+						"ipv6Prefix": "2600:1f00::/24",
+						"ipv6NewBits": 40,
+						"netNumCount": 2,
+						"offset": 1,
+						"debug": true
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"","kind":"","status": {"atFunction": {"cidr": [{"ipv4": "10.0.1.0/24", "ipv6": "2600:1f00:0:1::/64"}, {"ipv4": "10.0.2.0/24", "ipv6": "2600:1f00:0:2::/64"}]}}}`),
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `{"cidrFunc":"dualstackloop","parameters":[{"name":"cidrFunc","value":"dualstackloop"},{"name":"prefix","value":"10.0.0.0/16"},{"name":"newBits","value":[8]},{"name":"ipv6Prefix","value":"2600:1f00::/24"},{"name":"ipv6NewBits","value":40},{"name":"offset","value":1},{"name":"netNumItems","value":null},{"name":"netNumCount","value":2}],"intermediates":{"ipv6Pool":"2600:1f00::/24","netNums":[1,2],"pool":"10.0.0.0/16"},"outputField":"status.atFunction.cidr","output":[{"ipv4":"10.0.1.0/24","ipv6":"2600:1f00:0:1::/64"},{"ipv4":"10.0.2.0/24","ipv6":"2600:1f00:0:2::/64"}]}`,
+							Reason:   ptr("Debug"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: allocated("dualstackloop", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"dual-stack-loop-capacity": {
+			reason: "should fail before producing anything if one prefix lacks capacity",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "dualstackloop",
+						"prefix": "10.0.0.0/16",
+						"newBits": [8],
+						"ipv6Prefix": "2600:1f18:abcd:1200::/63",
+						"ipv6NewBits": 1,
+						"netNumCount": 3
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "cannot calculate dualstackloop for XNetwork: not enough IPv6 capacity for 3 dual-stack subnets: netnum 2 is out of range for prefix 2600:1f18:abcd:1200::/63 extended by 1 bits",
+							Reason:   ptr("NetNumOutOfRange"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("NetNumOutOfRange", "cannot calculate dualstackloop for XNetwork: not enough IPv6 capacity for 3 dual-stack subnets: netnum 2 is out of range for prefix 2600:1f18:abcd:1200::/63 extended by 1 bits"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"eui64": {
+			reason: "should build an address from a /64 prefix and the MAC address in a field",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "eui64",
+						"prefix": "2001:db8:1:2::/64",
+						"macField": "spec.mac"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XAppliance","spec":{"mac":"00:1a:2b:3c:4d:5e"}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XAppliance","status": {"atFunction": {"cidr": "2001:db8:1:2:21a:2bff:fe3c:4d5e"}}}`),
+						},
+					},
+					Conditions: allocated("eui64", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"nat64-invalid-prefix-length": {
+			reason: "should refuse NAT64 prefixes of lengths RFC 6052 does not allow",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "nat64",
+						"prefix": "64:ff9b::/80",
+						"ipv4Address": "192.0.2.33"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XBackend"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "cannot calculate nat64 for XBackend: invalid CIDR prefix \"64:ff9b::/80\": must be an IPv6 prefix of length [32 40 48 56 64 96]",
+							Reason:   ptr("InvalidPrefix"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("InvalidPrefix", "cannot calculate nat64 for XBackend: invalid CIDR prefix \"64:ff9b::/80\": must be an IPv6 prefix of length [32 40 48 56 64 96]"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"cidr-reverse-zone": {
+			reason: "should split a prefix that is not on an octet boundary into reverse zones",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "cidrreversezone",
+						"prefixField": "spec.cidrBlock"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XSubnet","spec":{"cidrBlock":"10.0.2.0/23"}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XSubnet","status": {"atFunction": {"cidr": ["2.0.10.in-addr.arpa", "3.0.10.in-addr.arpa"]}}}`),
+						},
+					},
+					Conditions: allocated("cidrreversezone", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"k8s-network": {
+			reason: "should plan the node, pod and service CIDRs of a cluster",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "k8snetwork",
+						"prefix": "10.0.0.0/12",
+						"k8sNetworkField": "spec.cluster"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster","spec":{"cluster":{"nodeCount":100,"maxPodsPerNode":110}}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster","status": {"atFunction": {"cidr": {
+								"nodeCIDR": "10.0.144.0/25",
+								"podCIDR": "10.0.0.0/17",
+								"serviceCIDR": "10.0.128.0/20",
+								"nodeCIDRMaskSize": 24,
+								"clusterDNSIP": "10.0.128.10",
+								"kubernetesServiceIP": "10.0.128.1"
+							}}}}`),
+						},
+					},
+					Conditions: allocated("k8snetwork", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"k8s-network-node-mask-too-long": {
+			reason: "should refuse a node CIDR mask without room for the pods of a node",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "k8snetwork",
+						"prefix": "10.0.0.0/12",
+						"k8sNetwork": {"nodeCount": 100, "maxPodsPerNode": 110, "nodeCIDRMaskSize": 26}
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "cannot calculate k8snetwork for XCluster: invalid node CIDR mask size 26: a node CIDR has room for fewer than 110 pods",
+							Reason:   ptr("InvalidNodeCIDRMask"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("InvalidNodeCIDRMask", "cannot calculate k8snetwork for XCluster: invalid node CIDR mask size 26: a node CIDR has room for fewer than 110 pods"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"gke-secondary-ranges": {
+			reason: "should allocate named GKE secondary ranges from the prefix",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "gkesecondaryranges",
+						"prefix": "10.0.0.0/16",
+						"secondaryRanges": [{"rangeName": "pods", "newBits": 2}, {"rangeName": "services", "newBits": 6}]
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster","status": {"atFunction": {"cidr": [
+								{"rangeName": "pods", "ipCidrRange": "10.0.0.0/18"},
+								{"rangeName": "services", "ipCidrRange": "10.0.64.0/22"}
+							]}}}`),
+						},
+					},
+					Conditions: allocated("gkesecondaryranges", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"eks-prefix-delegation": {
+			reason: "should calculate the max pods of EKS instance types with prefix delegation",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "eksprefixdelegation",
+						"prefix": "10.0.0.0/24",
+						"eksInstanceTypesField": "spec.instanceTypes"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster","spec":{"instanceTypes":[
+								{"name": "t3.micro", "enis": 2, "ipv4AddressesPerENI": 2, "vCPUs": 2},
+								{"name": "m5.large", "enis": 3, "ipv4AddressesPerENI": 10, "vCPUs": 2},
+								{"name": "m5.24xlarge", "enis": 15, "ipv4AddressesPerENI": 50, "vCPUs": 96}
+							]}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster","status": {"atFunction": {"cidr": {
+								"prefixLength": 28,
+								"prefixCount": 16,
+								"instanceTypes": {
+									"t3.micro": {"maxPods": 34, "prefixesPerNode": 2},
+									"m5.large": {"maxPods": 110, "prefixesPerNode": 7},
+									"m5.24xlarge": {"maxPods": 250, "prefixesPerNode": 16}
+								}
+							}}}}`),
+						},
+					},
+					Conditions: allocated("eksprefixdelegation", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"aks-cni-subnet": {
+			reason: "should size the node subnet of an AKS cluster using Azure CNI",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "akscnisubnet",
+						"prefix": "10.0.0.0/16",
+						"k8sNetwork": {"nodeCount": 10, "maxPodsPerNode": 30}
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster","status": {"atFunction": {"cidr": {
+								"subnetCIDR": "10.0.0.0/23",
+								"requiredIPs": 341,
+								"maxNodes": 15,
+								"maxPods": 30
+							}}}}`),
+						},
+					},
+					Conditions: allocated("akscnisubnet", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"aks-cni-subnet-ignores-services": {
+			reason: "should not validate the k8sNetwork fields akscnisubnet does not use",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "akscnisubnet",
+						"prefix": "10.0.0.0/16",
+						"k8sNetwork": {"nodeCount": 10, "maxPodsPerNode": 30, "serviceCount": 1, "nodeCIDRMaskSize": 200}
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster","status": {"atFunction": {"cidr": {
+								"subnetCIDR": "10.0.0.0/23",
+								"requiredIPs": 341,
+								"maxNodes": 15,
+								"maxPods": 30
+							}}}}`),
+						},
+					},
+					Conditions: allocated("akscnisubnet", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"aks-cni-subnet-exact-prefix": {
+			reason: "should use the whole prefix as the node subnet if it has the exact size",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "akscnisubnet",
+						"prefix": "10.0.0.0/23",
+						"k8sNetwork": {"nodeCount": 10, "maxPodsPerNode": 30}
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster","status": {"atFunction": {"cidr": {
+								"subnetCIDR": "10.0.0.0/23",
+								"requiredIPs": 341,
+								"maxNodes": 15,
+								"maxPods": 30
+							}}}}`),
+						},
+					},
+					Conditions: allocated("akscnisubnet", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"aks-cni-subnet-prefix-too-small": {
+			reason: "should fail if the prefix has no room for the node subnet",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "akscnisubnet",
+						"prefix": "10.0.0.0/24",
+						"k8sNetwork": {"nodeCount": 10, "maxPodsPerNode": 30}
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XCluster"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "cannot calculate akscnisubnet for XCluster: prefix 10.0.0.0/24 is too small for a node CIDR of length 23",
+							Reason:   ptr("PoolExhausted"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("PoolExhausted", "cannot calculate akscnisubnet for XCluster: prefix 10.0.0.0/24 is too small for a node CIDR of length 23"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"layout": {
+			reason: "should allocate a nested layout and return it as a tree and keyed by path",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "layout",
+						"prefix": "10.0.0.0/16",
+						"layout": [
+							{"name": "us-east-1", "newBits": 1, "children": [
+								{"name": "a", "newBits": 2, "children": [
+									{"name": "public", "hosts": 256},
+									{"name": "private", "newBits": 3}
+								]},
+								{"name": "b", "newBits": 2}
+							]},
+							{"name": "us-west-2", "newBits": 1}
+						]
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork"}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": {
+								"tree": {
+									"us-east-1": {"cidr": "10.0.0.0/17", "children": {
+										"a": {"cidr": "10.0.0.0/19", "children": {
+											"public": {"cidr": "10.0.0.0/24"},
+											"private": {"cidr": "10.0.4.0/22"}
+										}},
+										"b": {"cidr": "10.0.32.0/19"}
+									}},
+									"us-west-2": {"cidr": "10.0.128.0/17"}
+								},
+								"paths": {
+									"us-east-1": "10.0.0.0/17",
+									"us-east-1/a": "10.0.0.0/19",
+									"us-east-1/a/public": "10.0.0.0/24",
+									"us-east-1/a/private": "10.0.4.0/22",
+									"us-east-1/b": "10.0.32.0/19",
+									"us-west-2": "10.0.128.0/17"
+								}
+							}}}}`),
+						},
+					},
+					Conditions: allocated("layout", "status.atFunction.cidr"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"layout-children-exhausted": {
+			reason: "should fail if the children of a node do not fit into its CIDR",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "layout",
+						"prefix": "10.0.0.0/16",
+						"layoutField": "spec.layout"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","spec":{"layout":[
+								{"name": "us-east-1", "newBits": 1, "children": [
+									{"name": "a", "newBits": 1},
+									{"name": "b", "newBits": 1},
+									{"name": "c", "newBits": 1}
+								]}
+							]}}`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "cannot calculate layout for XNetwork: cannot allocate the children of us-east-1: not enough remaining address space in 10.0.0.0/17 for a subnet with a prefix of 18 bits after 10.0.64.0/18",
+							Reason:   ptr("PoolExhausted"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("PoolExhausted", "cannot calculate layout for XNetwork: cannot allocate the children of us-east-1: not enough remaining address space in 10.0.0.0/17 for a subnet with a prefix of 18 bits after 10.0.64.0/18"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"ula-prefix-netnumcount-too-large": {
+			reason: "should reject a netNumCount larger than the number of subnets of newBits",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "ulaprefix",
+						"seed": "prod",
+						"newBits": [2],
+						"netNumCount": 5
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "invalid Function input: parameters.netNumCount: Invalid value: 5: netNumCount must not exceed the number of subnets of newBits",
+							Reason:   ptr("InvalidInput"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("InvalidInput", "invalid Function input: parameters.netNumCount: Invalid value: 5: netNumCount must not exceed the number of subnets of newBits"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+		"ula-prefix-netnumcount-over-limit": {
+			reason: "should reject a netNumCount larger than the number of subnets ulaprefix computes",
+			args: args{
+				ctx: context.Background(),
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{
+						"cidrFunc": "ulaprefix",
+						"seed": "prod",
+						"newBits": [80],
+						"netNumCount": 1000000000000
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "invalid Function input: parameters.netNumCount: Invalid value: 1000000000000: netNumCount must not exceed 1024",
+							Reason:   ptr("InvalidInput"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: notAllocated("InvalidInput", "invalid Function input: parameters.netNumCount: Invalid value: 1000000000000: netNumCount must not exceed 1024"),
+					Meta:       responseMeta(),
+				},
+				err: nil,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := &Function{log: logging.NewNopLogger()}
+			rsp, err := f.RunFunction(tc.args.ctx, tc.args.req)
+
+			if diff := cmp.Diff(tc.want.rsp, rsp, protocmp.Transform()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// allocated returns the conditions of a response of the supplied cidrFunc that
+// wrote its result to outputField.
+func allocated(cidrFunc, outputField string) []*fnv1.Condition {
+	return []*fnv1.Condition{
+		{
+			Type:    "CIDRsAllocated",
+			Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+			Reason:  "Allocated",
+			Message: ptr("cidrFunc " + cidrFunc + " wrote its result to " + outputField),
+			Target:  fnv1.Target_TARGET_COMPOSITE.Enum(),
+		},
+	}
+}
+
+// notAllocated returns the conditions of a response that failed with the
+// supplied reason and message.
+func notAllocated(reason, message string) []*fnv1.Condition {
+	return []*fnv1.Condition{
+		{
+			Type:    "CIDRsAllocated",
+			Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+			Reason:  reason,
+			Message: ptr(message),
+			Target:  fnv1.Target_TARGET_COMPOSITE.Enum(),
+		},
+	}
+}
+
+// responseMeta returns the meta of every response.
+func responseMeta() *fnv1.ResponseMeta {
+	return &fnv1.ResponseMeta{
+		Ttl: &durationpb.Duration{
+			Seconds: 60,
+		},
+	}
+}
+
+// supportedValues returns the built-in cidrFuncs the way an Unsupported value
+// error lists them.
+func supportedValues() string {
+	names := SupportedCidrFuncs()
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
 	}
+	return strings.Join(quoted, ", ")
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func init() {
+	operation.DefaultRegistry.MustRegister("gkesecondaryranges", func() operation.CidrOperation { return &gkeSecondaryRangesOperation{} })
+}
+
+// ValidateGKESecondaryRangesParameters validates the Parameters object
+// in the context of gkesecondaryranges
+func ValidateGKESecondaryRangesParameters(p *v1beta1.Parameters) field.ErrorList {
+	path := field.NewPath("parameters")
+	switch {
+	case len(p.SecondaryRanges) > 0 && p.SecondaryRangesField != "":
+		return field.ErrorList{field.Forbidden(path.Child("secondaryRangesField"), "specify only one of secondaryRanges or secondaryRangesField to avoid ambiguous function input")}
+	case len(p.SecondaryRanges) == 0 && p.SecondaryRangesField == "":
+		return field.ErrorList{field.Required(path.Child("secondaryRanges"), "either secondaryRanges or secondaryRangesField function input is required")}
+	}
+	return validateSecondaryRanges(path.Child("secondaryRanges"), p.SecondaryRanges)
+}
+
+// validateSecondaryRanges validates that every secondary range has a unique,
+// RFC 1035 compliant name and extends the prefix by 1 to 32 bits.
+func validateSecondaryRanges(path *field.Path, ranges []v1beta1.SecondaryRange) field.ErrorList {
+	var errs field.ErrorList
+	seen := make(map[string]bool, len(ranges))
+	for i, sr := range ranges {
+		for _, msg := range validation.IsDNS1035Label(sr.RangeName) {
+			errs = append(errs, field.Invalid(path.Index(i).Child("rangeName"), sr.RangeName, msg))
+		}
+		if seen[sr.RangeName] {
+			errs = append(errs, field.Duplicate(path.Index(i).Child("rangeName"), sr.RangeName))
+		}
+		seen[sr.RangeName] = true
+		if sr.NewBits < 1 || sr.NewBits > cidr.Bits32 {
+			errs = append(errs, field.Invalid(path.Index(i).Child("newBits"), sr.NewBits, fmt.Sprintf("newBits must be between 1 and %d", cidr.Bits32)))
+		}
+	}
+	return errs
+}
+
+// gkeSecondaryRangesOperation allocates the named secondary IP ranges of a
+// GKE subnetwork, e.g. for pods and services, as consecutive subnets of the
+// prefix.
+type gkeSecondaryRangesOperation struct {
+	prefix  string
+	ranges  []v1beta1.SecondaryRange
+	pool    netip.Prefix
+	subnets []netip.Prefix
+}
+
+func (o *gkeSecondaryRangesOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, ValidateGKESecondaryRangesParameters(p)...)
+}
+
+func (o *gkeSecondaryRangesOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	o.ranges = p.SecondaryRanges
+	if err := r.Into("secondaryRanges", p.SecondaryRangesField, &o.ranges); err != nil {
+		return err
+	}
+	if len(o.ranges) == 0 {
+		return errors.Errorf("cidrFunc gkesecondaryranges requires secondaryRanges for %s", r.Kind())
+	}
+	if errs := validateSecondaryRanges(field.NewPath("secondaryRanges"), o.ranges); len(errs) > 0 {
+		return errors.Wrapf(errs.ToAggregate(), "invalid secondaryRanges for %s", r.Kind())
+	}
+	return nil
+}
+
+// Compute lays the secondary ranges out in the prefix like cidrsubnets does.
+func (o *gkeSecondaryRangesOperation) Compute() error {
+	var err error
+	if o.pool, err = cidr.ParsePrefix(o.prefix); err != nil {
+		return err
+	}
+	if !o.pool.Addr().Is4() {
+		return &cidr.InvalidPrefixError{Prefix: o.prefix, Err: errors.New("must be an IPv4 prefix")}
+	}
+	newBits := make([]int, len(o.ranges))
+	for i, sr := range o.ranges {
+		newBits[i] = sr.NewBits
+	}
+	o.subnets, err = cidr.Subnets(o.pool, newBits...)
+	return err
+}
+
+// Render returns the secondary ranges in the form of the secondaryIpRange
+// entries of a GKE subnetwork.
+func (o *gkeSecondaryRangesOperation) Render() (any, error) {
+	ranges := make([]any, len(o.subnets))
+	for i, s := range o.subnets {
+		ranges[i] = map[string]any{
+			"rangeName":   o.ranges[i].RangeName,
+			"ipCidrRange": s.String(),
+		}
+	}
+	return ranges, nil
+}
+
+func (o *gkeSecondaryRangesOperation) Intermediates() map[string]any {
+	return map[string]any{"pool": o.pool.String()}
+}
+
+func (o *gkeSecondaryRangesOperation) Allocations() map[netip.Prefix][]netip.Prefix {
+	return map[netip.Prefix][]netip.Prefix{o.pool: o.subnets}
+}
@@ -37,6 +37,166 @@ type MultiPrefix struct {
 	// +kubebuilder:validation:Maximum=32
 	// +kubebuilder:default=0
 	Offset int `json:"offset,omitempty"`
+
+	// Name is the key of the subnets of this entry in the output of the
+	// `multiprefixloop` function. Defaults to the prefix.
+	//
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Names are the keys of the subnets of this entry, one for each newBits
+	// element. The subnets are returned as a list if names is empty.
+	//
+	// +optional
+	// +listType=atomic
+	Names []string `json:"names,omitempty"`
+}
+
+// DriftCheck compares a computed CIDR with a field of an observed composed
+// resource.
+type DriftCheck struct {
+	// ResourceName is the name of the composed resource in the Composition.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	ResourceName string `json:"resourceName"`
+
+	// FieldPath is the path of the field of the observed composed resource
+	// that is compared with the computed value, e.g.
+	// status.atProvider.cidrBlock.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	FieldPath string `json:"fieldPath"`
+
+	// OutputPath selects the computed value to compare within the output of
+	// the function, e.g. [0] for the first subnet of a list. The whole output
+	// is compared if it is empty.
+	//
+	// +optional
+	OutputPath string `json:"outputPath,omitempty"`
+}
+
+// K8sNetwork describes the size of a Kubernetes cluster whose node, pod and
+// service CIDRs are planned by the `k8snetwork` function.
+type K8sNetwork struct {
+	// NodeCount is the number of nodes the cluster must have room for.
+	//
+	// +required
+	// +kubebuilder:validation:Minimum=1
+	NodeCount int64 `json:"nodeCount"`
+
+	// MaxPodsPerNode is the maximum number of pods per node.
+	//
+	// +required
+	// +kubebuilder:validation:Minimum=1
+	MaxPodsPerNode int64 `json:"maxPodsPerNode"`
+
+	// ServiceCount is the number of service IPs the cluster must have room
+	// for. Defaults to 4096.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=11
+	ServiceCount int64 `json:"serviceCount,omitempty"`
+
+	// NodeCIDRMaskSize is the mask size of the pod CIDR of each node, i.e.
+	// the --node-cidr-mask-size of the kube-controller-manager. Defaults to
+	// the longest mask with at least twice maxPodsPerNode addresses.
+	//
+	// +optional
+	NodeCIDRMaskSize int `json:"nodeCIDRMaskSize,omitempty"`
+}
+
+// SecondaryRange describes a named secondary IP range of a GKE subnetwork.
+type SecondaryRange struct {
+	// RangeName is the name of the secondary range, e.g. pods.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	RangeName string `json:"rangeName"`
+
+	// NewBits is the number of bits to extend the prefix by for this range.
+	//
+	// +required
+	// +kubebuilder:validation:Minimum=1
+	NewBits int `json:"newBits"`
+}
+
+// EKSInstanceType describes the network limits of an EC2 instance type.
+type EKSInstanceType struct {
+	// Name of the instance type, e.g. m5.large.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// ENIs is the maximum number of network interfaces of the instance type.
+	//
+	// +required
+	// +kubebuilder:validation:Minimum=1
+	ENIs int64 `json:"enis"`
+
+	// IPv4AddressesPerENI is the maximum number of IPv4 addresses per
+	// network interface of the instance type.
+	//
+	// +required
+	// +kubebuilder:validation:Minimum=1
+	IPv4AddressesPerENI int64 `json:"ipv4AddressesPerENI"`
+
+	// VCPUs is the number of vCPUs of the instance type.
+	//
+	// +required
+	// +kubebuilder:validation:Minimum=1
+	VCPUs int64 `json:"vCPUs"`
+}
+
+// LayoutNode is a node of the network layout the `layout` function allocates,
+// e.g. a region, an availability zone or a tier.
+type LayoutNode struct {
+	// Name of the node. It must be unique among its siblings.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// NewBits is the number of bits to extend the CIDR of the parent node by.
+	// Specify only one of newBits or hosts.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	NewBits int `json:"newBits,omitempty"`
+
+	// Hosts is the number of addresses the node needs. The node gets the
+	// longest prefix with at least that many addresses. Specify only one of
+	// newBits or hosts.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Hosts int64 `json:"hosts,omitempty"`
+
+	// Children are allocated from the CIDR of the node in order.
+	//
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=array
+	Children []LayoutNode `json:"children,omitempty"`
+}
+
+// SubnetItem is an item of the `cidrsubnetloop` function with its own
+// newBits.
+type SubnetItem struct {
+	// Name of the item, e.g. an availability zone.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// NewBits is the number of bits to extend the prefix by for this item.
+	//
+	// +required
+	// +kubebuilder:validation:Minimum=1
+	NewBits int `json:"newBits"`
 }
 
 // Parameters can be used to provide input to this Function.
@@ -56,7 +216,7 @@ type Parameters struct {
 	//
 	// +optional
 	// +kubebuilder:validation:Type=string
-	// +kubebuilder:validation:Enum={cidrhost,cidrnetmask,cidrsubnet,cidrsubnets,cidrsubnetloop,multiprefixloop}
+	// +kubebuilder:validation:Enum={akscnisubnet,cidrhost,cidrnetmask,cidrreversezone,cidrsubnet,cidrsubnets,cidrsubnetloop,cidrsubnethash,dualstackloop,eksprefixdelegation,eui64,gkesecondaryranges,ipv4mapped,k8snetwork,layout,multiprefixloop,nat64,ulaprefix}
 	CidrFunc string `json:"cidrFunc"`
 
 	// cidrFuncField is a reference to a location on the claim specifying the
@@ -152,6 +312,21 @@ type Parameters struct {
 	// +optional
 	NetNumItems []string `json:"netNumItems,omitempty"`
 
+	// subnetItemsField points to a field on the claim that contains the
+	// subnetItems.
+	//
+	// +optional
+	SubnetItemsField string `json:"subnetItemsField,omitempty"`
+
+	// subnetItems is an array of items with their own newBits the
+	// `cidrsubnetloop` function creates a network for. It is an alternative
+	// to netNumItems and newBits. The networks are keyed by the name of their
+	// item.
+	//
+	// +optional
+	// +listType=atomic
+	SubnetItems []SubnetItem `json:"subnetItems,omitempty"`
+
 	// offsetField defines a location on the claim to take the offset from
 	//
 	// This field is mutually exclusive with netNumCount and netNumItems
@@ -167,6 +342,164 @@ type Parameters struct {
 	// +optional
 	Offset int `json:"offset,omitempty"`
 
+	// ipv6PrefixField defines a location on the claim to take the ipv6Prefix
+	// from.
+	//
+	// +optional
+	IPv6PrefixField string `json:"ipv6PrefixField,omitempty"`
+
+	// ipv6Prefix is the IPv6 CIDR block the `dualstackloop` function pairs
+	// with the IPv4 prefix.
+	//
+	// +optional
+	IPv6Prefix string `json:"ipv6Prefix,omitempty"`
+
+	// ipv6NewBitsField points to a field on the claim that contains the
+	// ipv6NewBits.
+	//
+	// +optional
+	IPv6NewBitsField string `json:"ipv6NewBitsField,omitempty"`
+
+	// ipv6NewBits is the number of additional bits with which the
+	// `dualstackloop` function extends the ipv6Prefix.
+	//
+	// +optional
+	IPv6NewBits int `json:"ipv6NewBits,omitempty"`
+
+	// macField points to a field on the claim that contains the mac.
+	//
+	// +optional
+	MACField string `json:"macField,omitempty"`
+
+	// mac is the 48 or 64 bit MAC address the `eui64` function builds the
+	// interface identifier of an address from, e.g. 00:1a:2b:3c:4d:5e.
+	//
+	// +optional
+	MAC string `json:"mac,omitempty"`
+
+	// ipv4AddressField points to a field on the claim that contains the
+	// ipv4Address.
+	//
+	// +optional
+	IPv4AddressField string `json:"ipv4AddressField,omitempty"`
+
+	// ipv4Address is the IPv4 address the `nat64` and `ipv4mapped` functions
+	// embed into an IPv6 address.
+	//
+	// +optional
+	IPv4Address string `json:"ipv4Address,omitempty"`
+
+	// k8sNetworkField points to a field on the claim that contains the
+	// k8sNetwork.
+	//
+	// +optional
+	K8sNetworkField string `json:"k8sNetworkField,omitempty"`
+
+	// k8sNetwork describes the cluster the `k8snetwork` function plans the
+	// node, pod and service CIDRs of, and the `akscnisubnet` function sizes
+	// the node subnet of.
+	//
+	// +optional
+	K8sNetwork *K8sNetwork `json:"k8sNetwork,omitempty"`
+
+	// secondaryRangesField points to a field on the claim that contains the
+	// secondaryRanges.
+	//
+	// +optional
+	SecondaryRangesField string `json:"secondaryRangesField,omitempty"`
+
+	// secondaryRanges are the named secondary IP ranges the
+	// `gkesecondaryranges` function allocates from the prefix.
+	//
+	// +optional
+	// +listType=atomic
+	SecondaryRanges []SecondaryRange `json:"secondaryRanges,omitempty"`
+
+	// eksInstanceTypesField points to a field on the claim that contains the
+	// eksInstanceTypes.
+	//
+	// +optional
+	EKSInstanceTypesField string `json:"eksInstanceTypesField,omitempty"`
+
+	// eksInstanceTypes is the table of instance types the
+	// `eksprefixdelegation` function calculates the max pods of.
+	//
+	// +optional
+	// +listType=atomic
+	EKSInstanceTypes []EKSInstanceType `json:"eksInstanceTypes,omitempty"`
+
+	// layoutField points to a field on the claim that contains the layout.
+	//
+	// +optional
+	LayoutField string `json:"layoutField,omitempty"`
+
+	// layout is the tree of nodes, e.g. regions, availability zones and
+	// tiers, the `layout` function allocates from the prefix.
+	//
+	// +optional
+	// +listType=atomic
+	Layout []LayoutNode `json:"layout,omitempty"`
+
+	// hashKeyField points to a field on the claim that contains the hashKey.
+	//
+	// +optional
+	HashKeyField string `json:"hashKeyField,omitempty"`
+
+	// hashKey is hashed into the netNum space of newBits by the
+	// `cidrsubnethash` function. The UID of the composite resource is used if
+	// neither hashKey nor hashKeyField is specified.
+	//
+	// +optional
+	HashKey string `json:"hashKey,omitempty"`
+
+	// usedCIDRsField points to a field on the claim that contains the
+	// usedCIDRs.
+	//
+	// +optional
+	UsedCIDRsField string `json:"usedCIDRsField,omitempty"`
+
+	// usedCIDRs is a list of CIDR blocks that are already in use. The
+	// `cidrsubnethash` function probes the following netNums if the subnet a
+	// key hashes to overlaps any of them, the `cidrsubnetloop` function skips
+	// the netNums of subnets that overlap any of them and the `cidrsubnets`
+	// function routes around them.
+	//
+	// +optional
+	// +listType=atomic
+	UsedCIDRs []string `json:"usedCIDRs,omitempty"`
+
+	// reservedNetNumsField points to a field on the claim that contains the
+	// reservedNetNums.
+	//
+	// +optional
+	ReservedNetNumsField string `json:"reservedNetNumsField,omitempty"`
+
+	// reservedNetNums is a list of netNums the `cidrsubnetloop` function
+	// skips while it still creates netNumCount networks.
+	//
+	// +optional
+	// +listType=atomic
+	ReservedNetNums []int64 `json:"reservedNetNums,omitempty"`
+
+	// maxProbes limits how many subnets the `cidrsubnethash` function probes
+	// before giving up. At most 4096 subnets are probed if it is 0.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxProbes int64 `json:"maxProbes,omitempty"`
+
+	// seedField points to a field on the claim that contains the seed.
+	//
+	// +optional
+	SeedField string `json:"seedField,omitempty"`
+
+	// seed is hashed into the Global ID of the RFC 4193 Unique Local Address
+	// prefix returned by the `ulaprefix` function. The UID of the composite
+	// resource is used if neither seed nor seedField is specified.
+	//
+	// +optional
+	Seed string `json:"seed,omitempty"`
+
 	// outputField specifies a location on the XR to patch the results of the
 	// function call to.
 	//
@@ -175,4 +508,51 @@ type Parameters struct {
 	//
 	// +optional
 	OutputField string `json:"outputField,omitempty"`
+
+	// debug attaches a Normal result to the response that lists every
+	// resolved parameter, the field it was read from and the intermediate
+	// values of the calculation.
+	//
+	// +optional
+	Debug bool `json:"debug,omitempty"`
+
+	// debugContextKey additionally writes the debug output to this key of the
+	// pipeline context. It is only used if debug is true.
+	//
+	// +optional
+	DebugContextKey string `json:"debugContextKey,omitempty"`
+
+	// driftChecks compare the computed CIDRs with fields of observed composed
+	// resources. Every mismatch is reported as a warning and sets the
+	// CIDRsInSync condition of the composite resource to false.
+	//
+	// +optional
+	// +listType=atomic
+	DriftChecks []DriftCheck `json:"driftChecks,omitempty"`
+
+	// lock keeps the value already published at outputField of the observed
+	// composite resource if a new calculation differs from it, e.g. because
+	// the prefix was changed by accident.
+	//
+	// +optional
+	Lock bool `json:"lock,omitempty"`
+
+	// lockPolicy determines how a calculation that differs from a locked
+	// output is reported. Warning keeps the locked output and emits a warning,
+	// Fatal fails the function. It is only used if lock is true.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=Warning;Fatal
+	// +kubebuilder:default=Warning
+	LockPolicy LockPolicy `json:"lockPolicy,omitempty"`
 }
+
+// LockPolicy determines how a calculation that differs from a locked output is
+// reported.
+type LockPolicy string
+
+// Supported lock policies.
+const (
+	LockPolicyWarning LockPolicy = "Warning"
+	LockPolicyFatal   LockPolicy = "Fatal"
+)
@@ -8,6 +8,73 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftCheck) DeepCopyInto(out *DriftCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftCheck.
+func (in *DriftCheck) DeepCopy() *DriftCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EKSInstanceType) DeepCopyInto(out *EKSInstanceType) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKSInstanceType.
+func (in *EKSInstanceType) DeepCopy() *EKSInstanceType {
+	if in == nil {
+		return nil
+	}
+	out := new(EKSInstanceType)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K8sNetwork) DeepCopyInto(out *K8sNetwork) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K8sNetwork.
+func (in *K8sNetwork) DeepCopy() *K8sNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(K8sNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LayoutNode) DeepCopyInto(out *LayoutNode) {
+	*out = *in
+	if in.Children != nil {
+		in, out := &in.Children, &out.Children
+		*out = make([]LayoutNode, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LayoutNode.
+func (in *LayoutNode) DeepCopy() *LayoutNode {
+	if in == nil {
+		return nil
+	}
+	out := new(LayoutNode)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MultiPrefix) DeepCopyInto(out *MultiPrefix) {
 	*out = *in
@@ -16,6 +83,11 @@ func (in *MultiPrefix) DeepCopyInto(out *MultiPrefix) {
 		*out = make([]int, len(*in))
 		copy(*out, *in)
 	}
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultiPrefix.
@@ -50,6 +122,48 @@ func (in *Parameters) DeepCopyInto(out *Parameters) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.SubnetItems != nil {
+		in, out := &in.SubnetItems, &out.SubnetItems
+		*out = make([]SubnetItem, len(*in))
+		copy(*out, *in)
+	}
+	if in.K8sNetwork != nil {
+		in, out := &in.K8sNetwork, &out.K8sNetwork
+		*out = new(K8sNetwork)
+		**out = **in
+	}
+	if in.SecondaryRanges != nil {
+		in, out := &in.SecondaryRanges, &out.SecondaryRanges
+		*out = make([]SecondaryRange, len(*in))
+		copy(*out, *in)
+	}
+	if in.EKSInstanceTypes != nil {
+		in, out := &in.EKSInstanceTypes, &out.EKSInstanceTypes
+		*out = make([]EKSInstanceType, len(*in))
+		copy(*out, *in)
+	}
+	if in.Layout != nil {
+		in, out := &in.Layout, &out.Layout
+		*out = make([]LayoutNode, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UsedCIDRs != nil {
+		in, out := &in.UsedCIDRs, &out.UsedCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReservedNetNums != nil {
+		in, out := &in.ReservedNetNums, &out.ReservedNetNums
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+	if in.DriftChecks != nil {
+		in, out := &in.DriftChecks, &out.DriftChecks
+		*out = make([]DriftCheck, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Parameters.
@@ -69,3 +183,33 @@ func (in *Parameters) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecondaryRange) DeepCopyInto(out *SecondaryRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecondaryRange.
+func (in *SecondaryRange) DeepCopy() *SecondaryRange {
+	if in == nil {
+		return nil
+	}
+	out := new(SecondaryRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SubnetItem) DeepCopyInto(out *SubnetItem) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubnetItem.
+func (in *SubnetItem) DeepCopy() *SubnetItem {
+	if in == nil {
+		return nil
+	}
+	out := new(SubnetItem)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func init() {
+	operation.DefaultRegistry.MustRegister("eui64", func() operation.CidrOperation { return &eui64Operation{} })
+	operation.DefaultRegistry.MustRegister("nat64", func() operation.CidrOperation { return &nat64Operation{} })
+	operation.DefaultRegistry.MustRegister("ipv4mapped", func() operation.CidrOperation { return &ipv4MappedOperation{} })
+}
+
+// validateMACParameter validates the mac parameter.
+func validateMACParameter(p *v1beta1.Parameters) field.ErrorList {
+	path := field.NewPath("parameters")
+	switch {
+	case p.MAC != "" && p.MACField != "":
+		return field.ErrorList{field.Forbidden(path.Child("macField"), "specify only one of mac or macField to avoid ambiguous function input")}
+	case p.MAC == "" && p.MACField == "":
+		return field.ErrorList{field.Required(path.Child("mac"), "either mac or macField function input is required")}
+	case p.MAC != "":
+		if _, err := net.ParseMAC(p.MAC); err != nil {
+			return field.ErrorList{field.Invalid(path.Child("mac"), p.MAC, "invalid MAC address")}
+		}
+	}
+	return nil
+}
+
+// validateIPv4AddressParameter validates the ipv4Address parameter.
+func validateIPv4AddressParameter(p *v1beta1.Parameters) field.ErrorList {
+	path := field.NewPath("parameters")
+	switch {
+	case p.IPv4Address != "" && p.IPv4AddressField != "":
+		return field.ErrorList{field.Forbidden(path.Child("ipv4AddressField"), "specify only one of ipv4Address or ipv4AddressField to avoid ambiguous function input")}
+	case p.IPv4Address == "" && p.IPv4AddressField == "":
+		return field.ErrorList{field.Required(path.Child("ipv4Address"), "either ipv4Address or ipv4AddressField function input is required")}
+	case p.IPv4Address != "":
+		if a, err := netip.ParseAddr(p.IPv4Address); err != nil || !a.Is4() {
+			return field.ErrorList{field.Invalid(path.Child("ipv4Address"), p.IPv4Address, "invalid IPv4 address")}
+		}
+	}
+	return nil
+}
+
+// parseIPv4Address parses s as an address for the cidr package.
+func parseIPv4Address(s string) (netip.Addr, error) {
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, &cidr.InvalidAddressError{Address: s, Message: err.Error()}
+	}
+	return a, nil
+}
+
+// eui64Operation calculates the address of a /64 prefix whose interface
+// identifier is the modified EUI-64 of a MAC address.
+type eui64Operation struct {
+	prefix string
+	mac    string
+	host   netip.Addr
+}
+
+func (o *eui64Operation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, validateMACParameter(p)...)
+}
+
+func (o *eui64Operation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	o.mac, err = r.String("mac", p.MAC, p.MACField)
+	return err
+}
+
+func (o *eui64Operation) Compute() error {
+	prefix, err := cidr.ParsePrefix(o.prefix)
+	if err != nil {
+		return err
+	}
+	mac, err := net.ParseMAC(o.mac)
+	if err != nil {
+		return &cidr.InvalidAddressError{Address: o.mac, Message: err.Error()}
+	}
+	o.host, err = cidr.EUI64(prefix, mac)
+	return err
+}
+
+func (o *eui64Operation) Render() (any, error) {
+	return o.host.String(), nil
+}
+
+// nat64Operation calculates the IPv4-embedded IPv6 address of an IPv4 address
+// in a NAT64 prefix.
+type nat64Operation struct {
+	prefix      string
+	ipv4Address string
+	host        netip.Addr
+}
+
+func (o *nat64Operation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, validateIPv4AddressParameter(p)...)
+}
+
+func (o *nat64Operation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	o.ipv4Address, err = r.String("ipv4Address", p.IPv4Address, p.IPv4AddressField)
+	return err
+}
+
+func (o *nat64Operation) Compute() error {
+	prefix, err := cidr.ParsePrefix(o.prefix)
+	if err != nil {
+		return err
+	}
+	v4, err := parseIPv4Address(o.ipv4Address)
+	if err != nil {
+		return err
+	}
+	o.host, err = cidr.NAT64(prefix, v4)
+	return err
+}
+
+func (o *nat64Operation) Render() (any, error) {
+	return o.host.String(), nil
+}
+
+// ipv4MappedOperation calculates the IPv4-mapped IPv6 address of an IPv4
+// address.
+type ipv4MappedOperation struct {
+	ipv4Address string
+	host        netip.Addr
+}
+
+func (o *ipv4MappedOperation) Validate(p *v1beta1.Parameters, _ *operation.Resolver) field.ErrorList {
+	return validateIPv4AddressParameter(p)
+}
+
+func (o *ipv4MappedOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	o.ipv4Address, err = r.String("ipv4Address", p.IPv4Address, p.IPv4AddressField)
+	return err
+}
+
+func (o *ipv4MappedOperation) Compute() error {
+	v4, err := parseIPv4Address(o.ipv4Address)
+	if err != nil {
+		return err
+	}
+	o.host, err = cidr.IPv4Mapped(v4)
+	return err
+}
+
+func (o *ipv4MappedOperation) Render() (any, error) {
+	return o.host.String(), nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"net/netip"
+	"sort"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func init() {
+	operation.DefaultRegistry.MustRegister("k8snetwork", func() operation.CidrOperation { return &k8sNetworkOperation{} })
+}
+
+const (
+	// defaultServiceCount is the number of service IPs planned unless the
+	// serviceCount is set.
+	defaultServiceCount = 4096
+
+	// reservedNodeAddresses is the number of addresses of the node CIDR that
+	// cannot be assigned to nodes, i.e. the network and broadcast address.
+	reservedNodeAddresses = 2
+
+	// Host numbers of well-known addresses of the service CIDR.
+	kubernetesServiceHostNum = 1
+	clusterDNSHostNum        = 10
+
+	// reasonInvalidNodeCIDRMask is the reason of a NodeCIDRMaskError.
+	reasonInvalidNodeCIDRMask = "InvalidNodeCIDRMask"
+)
+
+// A NodeCIDRMaskError is returned if the node CIDR mask size does not fit the
+// pod CIDR or the pods of a node.
+type NodeCIDRMaskError struct {
+	NodeCIDRMaskSize int
+	Message          string
+}
+
+func (e *NodeCIDRMaskError) Error() string {
+	return fmt.Sprintf("invalid node CIDR mask size %d: %s", e.NodeCIDRMaskSize, e.Message)
+}
+
+// Reason returns the reason of the error.
+func (e *NodeCIDRMaskError) Reason() string { return reasonInvalidNodeCIDRMask }
+
+// A PrefixTooSmallError is returned if a prefix is too small to hold a CIDR of
+// the requested length.
+type PrefixTooSmallError struct {
+	Prefix netip.Prefix
+	Name   string
+	Length int
+}
+
+func (e *PrefixTooSmallError) Error() string {
+	return fmt.Sprintf("prefix %s is too small for a %s CIDR of length %d", e.Prefix, e.Name, e.Length)
+}
+
+// Reason returns the reason of the error.
+func (e *PrefixTooSmallError) Reason() string { return cidr.ReasonPoolExhausted }
+
+// ValidateK8sNetworkParameters validates the Parameters object
+// in the context of k8snetwork
+func ValidateK8sNetworkParameters(p *v1beta1.Parameters) field.ErrorList {
+	return validateK8sNetworkParameters(p, validateK8sNetwork)
+}
+
+// validateK8sNetworkParameters validates that exactly one of k8sNetwork or
+// k8sNetworkField is specified, and validates a literal k8sNetwork using the
+// supplied function.
+func validateK8sNetworkParameters(p *v1beta1.Parameters, validate func(*field.Path, v1beta1.K8sNetwork) field.ErrorList) field.ErrorList {
+	path := field.NewPath("parameters")
+	switch {
+	case p.K8sNetwork != nil && p.K8sNetworkField != "":
+		return field.ErrorList{field.Forbidden(path.Child("k8sNetworkField"), "specify only one of k8sNetwork or k8sNetworkField to avoid ambiguous function input")}
+	case p.K8sNetwork == nil && p.K8sNetworkField == "":
+		return field.ErrorList{field.Required(path.Child("k8sNetwork"), "either k8sNetwork or k8sNetworkField function input is required")}
+	case p.K8sNetwork == nil:
+		return nil
+	}
+	return validate(path.Child("k8sNetwork"), *p.K8sNetwork)
+}
+
+// validateK8sNetwork validates the static parts of a K8sNetwork.
+func validateK8sNetwork(path *field.Path, n v1beta1.K8sNetwork) field.ErrorList {
+	errs := validateK8sNodes(path, n)
+	if n.ServiceCount != 0 && n.ServiceCount <= clusterDNSHostNum {
+		errs = append(errs, field.Invalid(path.Child("serviceCount"), n.ServiceCount, fmt.Sprintf("serviceCount must be larger than %d to hold the cluster DNS IP", clusterDNSHostNum)))
+	}
+	if n.NodeCIDRMaskSize < 0 || n.NodeCIDRMaskSize > cidr.Bits128 {
+		errs = append(errs, field.Invalid(path.Child("nodeCIDRMaskSize"), n.NodeCIDRMaskSize, fmt.Sprintf("nodeCIDRMaskSize must be between 0 and %d", cidr.Bits128)))
+	}
+	return errs
+}
+
+// validateK8sNodes validates the node count and the max pods per node of a
+// K8sNetwork.
+func validateK8sNodes(path *field.Path, n v1beta1.K8sNetwork) field.ErrorList {
+	var errs field.ErrorList
+	if n.NodeCount < 1 {
+		errs = append(errs, field.Invalid(path.Child("nodeCount"), n.NodeCount, "nodeCount must be at least 1"))
+	}
+	if n.MaxPodsPerNode < 1 {
+		errs = append(errs, field.Invalid(path.Child("maxPodsPerNode"), n.MaxPodsPerNode, "maxPodsPerNode must be at least 1"))
+	}
+	return errs
+}
+
+// k8sNetworkOperation plans the node, pod and service CIDRs of a Kubernetes
+// cluster within a prefix.
+type k8sNetworkOperation struct {
+	prefix       string
+	network      v1beta1.K8sNetwork
+	pool         netip.Prefix
+	nodeMaskSize int
+	nodeCIDR     netip.Prefix
+	podCIDR      netip.Prefix
+	serviceCIDR  netip.Prefix
+	clusterDNSIP netip.Addr
+	apiServiceIP netip.Addr
+}
+
+func (o *k8sNetworkOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, ValidateK8sNetworkParameters(p)...)
+}
+
+func (o *k8sNetworkOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	if p.K8sNetwork != nil {
+		o.network = *p.K8sNetwork
+	}
+	if err := r.Into("k8sNetwork", p.K8sNetworkField, &o.network); err != nil {
+		return err
+	}
+	if errs := validateK8sNetwork(field.NewPath("k8sNetwork"), o.network); len(errs) > 0 {
+		return errors.Wrapf(errs.ToAggregate(), "invalid k8sNetwork for %s", r.Kind())
+	}
+	if o.network.ServiceCount == 0 {
+		o.network.ServiceCount = defaultServiceCount
+	}
+	return nil
+}
+
+// Compute sizes the node CIDR for the nodes, the pod CIDR for a node CIDR mask
+// per node and the service CIDR for the services, and lays them out in the
+// prefix largest first so that aligning them wastes no addresses.
+func (o *k8sNetworkOperation) Compute() error {
+	var err error
+	if o.pool, err = cidr.ParsePrefix(o.prefix); err != nil {
+		return err
+	}
+	addrLen := o.pool.Addr().BitLen()
+
+	o.nodeMaskSize = o.network.NodeCIDRMaskSize
+	if o.nodeMaskSize == 0 {
+		o.nodeMaskSize = addrLen - bitsFor(2*o.network.MaxPodsPerNode)
+	}
+	if o.nodeMaskSize > addrLen {
+		return &NodeCIDRMaskError{NodeCIDRMaskSize: o.nodeMaskSize, Message: fmt.Sprintf("must not be longer than the %d bits of an %s address", addrLen, cidr.Protocol(o.pool))}
+	}
+	if addrLen-o.nodeMaskSize < bitsFor(o.network.MaxPodsPerNode) {
+		return &NodeCIDRMaskError{NodeCIDRMaskSize: o.nodeMaskSize, Message: fmt.Sprintf("a node CIDR has room for fewer than %d pods", o.network.MaxPodsPerNode)}
+	}
+
+	lengths := map[string]int{
+		"pod":     o.nodeMaskSize - bitsFor(o.network.NodeCount),
+		"service": addrLen - bitsFor(o.network.ServiceCount),
+		"node":    addrLen - bitsFor(o.network.NodeCount+reservedNodeAddresses),
+	}
+	if lengths["pod"] < 0 {
+		return &NodeCIDRMaskError{NodeCIDRMaskSize: o.nodeMaskSize, Message: fmt.Sprintf("a pod CIDR cannot hold %d node CIDRs", o.network.NodeCount)}
+	}
+
+	names := []string{"pod", "service", "node"}
+	sort.SliceStable(names, func(i, j int) bool { return lengths[names[i]] < lengths[names[j]] })
+	newBits := make([]int, len(names))
+	for i, name := range names {
+		if lengths[name] <= o.pool.Bits() {
+			return &PrefixTooSmallError{Prefix: o.pool, Name: name, Length: lengths[name]}
+		}
+		newBits[i] = lengths[name] - o.pool.Bits()
+	}
+
+	subnets, err := cidr.Subnets(o.pool, newBits...)
+	if err != nil {
+		return err
+	}
+	cidrs := map[string]*netip.Prefix{"pod": &o.podCIDR, "service": &o.serviceCIDR, "node": &o.nodeCIDR}
+	for i, name := range names {
+		*cidrs[name] = subnets[i]
+	}
+
+	if o.apiServiceIP, err = cidr.Host(o.serviceCIDR, kubernetesServiceHostNum); err != nil {
+		return err
+	}
+	o.clusterDNSIP, err = cidr.Host(o.serviceCIDR, clusterDNSHostNum)
+	return err
+}
+
+func (o *k8sNetworkOperation) Render() (any, error) {
+	return map[string]any{
+		"nodeCIDR":            o.nodeCIDR.String(),
+		"podCIDR":             o.podCIDR.String(),
+		"serviceCIDR":         o.serviceCIDR.String(),
+		"nodeCIDRMaskSize":    o.nodeMaskSize,
+		"clusterDNSIP":        o.clusterDNSIP.String(),
+		"kubernetesServiceIP": o.apiServiceIP.String(),
+	}, nil
+}
+
+func (o *k8sNetworkOperation) Intermediates() map[string]any {
+	return map[string]any{"pool": o.pool.String(), "nodeCIDRMaskSize": o.nodeMaskSize, "serviceCount": o.network.ServiceCount}
+}
+
+func (o *k8sNetworkOperation) Allocations() map[netip.Prefix][]netip.Prefix {
+	return map[netip.Prefix][]netip.Prefix{o.pool: {o.podCIDR, o.serviceCIDR, o.nodeCIDR}}
+}
+
+// bitsFor returns the number of bits needed to number n items.
+func bitsFor(n int64) int {
+	if n <= 1 {
+		return 0
+	}
+	return bits.Len64(uint64(n - 1))
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func init() {
+	operation.DefaultRegistry.MustRegister("layout", func() operation.CidrOperation { return &layoutOperation{} })
+}
+
+// layoutPathSeparator separates the names of the nodes in the path of a
+// layout node, e.g. us-east-1/a/private.
+const layoutPathSeparator = "/"
+
+// ValidateLayoutParameters validates the Parameters object
+// in the context of layout
+func ValidateLayoutParameters(p *v1beta1.Parameters) field.ErrorList {
+	path := field.NewPath("parameters")
+	switch {
+	case len(p.Layout) > 0 && p.LayoutField != "":
+		return field.ErrorList{field.Forbidden(path.Child("layoutField"), "specify only one of layout or layoutField to avoid ambiguous function input")}
+	case len(p.Layout) == 0 && p.LayoutField == "":
+		return field.ErrorList{field.Required(path.Child("layout"), "either layout or layoutField function input is required")}
+	}
+	return validateLayout(path.Child("layout"), p.Layout)
+}
+
+// validateLayout validates that every node of the layout has a name that is
+// unique among its siblings and exactly one of newBits or hosts.
+func validateLayout(path *field.Path, nodes []v1beta1.LayoutNode) field.ErrorList {
+	var errs field.ErrorList
+	seen := make(map[string]bool, len(nodes))
+	for i, n := range nodes {
+		np := path.Index(i)
+		switch {
+		case n.Name == "":
+			errs = append(errs, field.Required(np.Child("name"), "name is required"))
+		case strings.Contains(n.Name, layoutPathSeparator):
+			errs = append(errs, field.Invalid(np.Child("name"), n.Name, fmt.Sprintf("name must not contain %q", layoutPathSeparator)))
+		case seen[n.Name]:
+			errs = append(errs, field.Duplicate(np.Child("name"), n.Name))
+		}
+		seen[n.Name] = true
+
+		switch {
+		case n.NewBits != 0 && n.Hosts != 0:
+			errs = append(errs, field.Forbidden(np.Child("hosts"), "specify only one of newBits or hosts to avoid ambiguous function input"))
+		case n.NewBits == 0 && n.Hosts == 0:
+			errs = append(errs, field.Required(np.Child("newBits"), "either newBits or hosts is required"))
+		case n.NewBits < 0 || n.NewBits > cidr.Bits32:
+			errs = append(errs, field.Invalid(np.Child("newBits"), n.NewBits, fmt.Sprintf("newBits must be between 1 and %d", cidr.Bits32)))
+		case n.Hosts < 0:
+			errs = append(errs, field.Invalid(np.Child("hosts"), n.Hosts, "hosts must be at least 1"))
+		}
+
+		errs = append(errs, validateLayout(np.Child("children"), n.Children)...)
+	}
+	return errs
+}
+
+// layoutOperation allocates a tree of nodes, e.g. regions, availability zones
+// and tiers, from a prefix. The children of every node are laid out in the CIDR
+// of the node like cidrsubnets does.
+type layoutOperation struct {
+	prefix      string
+	nodes       []v1beta1.LayoutNode
+	pool        netip.Prefix
+	tree        map[string]any
+	paths       map[string]string
+	allocations map[netip.Prefix][]netip.Prefix
+}
+
+func (o *layoutOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	errs := ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+	return append(errs, ValidateLayoutParameters(p)...)
+}
+
+func (o *layoutOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	if o.prefix, err = r.Prefix(p.Prefix, p.PrefixField); err != nil {
+		return err
+	}
+	o.nodes = p.Layout
+	if err := r.Into("layout", p.LayoutField, &o.nodes); err != nil {
+		return err
+	}
+	if len(o.nodes) == 0 {
+		return errors.Errorf("cidrFunc layout requires a layout for %s", r.Kind())
+	}
+	if errs := validateLayout(field.NewPath("layout"), o.nodes); len(errs) > 0 {
+		return errors.Wrapf(errs.ToAggregate(), "invalid layout for %s", r.Kind())
+	}
+	return nil
+}
+
+func (o *layoutOperation) Compute() error {
+	var err error
+	if o.pool, err = cidr.ParsePrefix(o.prefix); err != nil {
+		return err
+	}
+	o.paths = make(map[string]string)
+	o.allocations = make(map[netip.Prefix][]netip.Prefix)
+	o.tree, err = o.allocate(o.pool, "", o.nodes)
+	return err
+}
+
+// allocate allocates the supplied nodes and their children from the CIDR of
+// their parent and returns them keyed by name.
+func (o *layoutOperation) allocate(parent netip.Prefix, parentPath string, nodes []v1beta1.LayoutNode) (map[string]any, error) {
+	newBits := make([]int, len(nodes))
+	for i, n := range nodes {
+		newBits[i] = n.NewBits
+		if n.Hosts == 0 {
+			continue
+		}
+		length := parent.Addr().BitLen() - bitsFor(n.Hosts)
+		if length <= parent.Bits() {
+			return nil, &PrefixTooSmallError{Prefix: parent, Name: layoutPath(parentPath, n.Name), Length: length}
+		}
+		newBits[i] = length - parent.Bits()
+	}
+
+	subnets, err := cidr.Subnets(parent, newBits...)
+	if err != nil {
+		if parentPath == "" {
+			return nil, errors.Wrap(err, "cannot allocate the layout")
+		}
+		return nil, errors.Wrapf(err, "cannot allocate the children of %s", parentPath)
+	}
+	o.allocations[parent] = subnets
+
+	tree := make(map[string]any, len(nodes))
+	for i, n := range nodes {
+		path := layoutPath(parentPath, n.Name)
+		o.paths[path] = subnets[i].String()
+
+		node := map[string]any{"cidr": subnets[i].String()}
+		if len(n.Children) > 0 {
+			children, err := o.allocate(subnets[i], path, n.Children)
+			if err != nil {
+				return nil, err
+			}
+			node["children"] = children
+		}
+		tree[n.Name] = node
+	}
+	return tree, nil
+}
+
+// Render returns the layout both as a tree of nodes keyed by name and as a
+// flat map of CIDRs keyed by the path of each node.
+func (o *layoutOperation) Render() (any, error) {
+	return map[string]any{
+		"tree":  o.tree,
+		"paths": o.paths,
+	}, nil
+}
+
+func (o *layoutOperation) Intermediates() map[string]any {
+	return map[string]any{"pool": o.pool.String()}
+}
+
+func (o *layoutOperation) Allocations() map[netip.Prefix][]netip.Prefix {
+	return o.allocations
+}
+
+// layoutPath returns the path of the named child of the node at parentPath.
+func layoutPath(parentPath, name string) string {
+	if parentPath == "" {
+		return name
+	}
+	return parentPath + layoutPathSeparator + name
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+// inputGroup is the API group of the input of this function.
+const inputGroup = "cidr.fn.crossplane.io"
+
+// LintCmd statically validates Compositions that use this function.
+type LintCmd struct {
+	Files []string `arg:"" help:"YAML files containing Compositions and the CompositeResourceDefinitions of their composite resources."`
+
+	FunctionName []string `default:"function-cidr,upbound-function-cidr" help:"Comma separated names this function is installed as. Pipeline steps with a functionRef to one of these names, or with a cidr.fn.crossplane.io input, are linted."`
+}
+
+// A Document is a single YAML document read from a file.
+type Document struct {
+	File string
+	Node *yaml.Node
+}
+
+// A Diagnostic is a problem found by the lint command.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// Run the lint command.
+func (c *LintCmd) Run(k *kong.Context) error {
+	var docs []Document
+	for _, f := range c.Files {
+		d, err := ReadDocuments(f)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, d...)
+	}
+
+	diags := Lint(operation.DefaultRegistry, c.FunctionName, docs)
+	for _, d := range diags {
+		fmt.Fprintln(k.Stdout, d)
+	}
+	if len(diags) > 0 {
+		return errors.Errorf("found %d problems", len(diags))
+	}
+	return nil
+}
+
+// ReadDocuments reads every YAML document of the supplied file.
+func ReadDocuments(path string) ([]Document, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []Document
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		n := &yaml.Node{}
+		err := dec.Decode(n)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot decode %s", path)
+		}
+		if len(n.Content) == 0 {
+			continue
+		}
+		docs = append(docs, Document{File: path, Node: n.Content[0]})
+	}
+}
+
+// Lint statically validates every pipeline step of the supplied Compositions
+// that uses this function. The Parameters of a step are validated against the
+// cidrFuncs of the supplied registry, and the *Field parameters that reference
+// the composite resource are looked up in the schema of its
+// CompositeResourceDefinition if one is among the supplied documents.
+func Lint(reg *operation.Registry, functionNames []string, docs []Document) []Diagnostic {
+	schemas := map[string]map[string]any{}
+	for _, d := range docs {
+		if kind(d.Node) != "CompositeResourceDefinition" {
+			continue
+		}
+		for k, s := range xrdSchemas(d.Node) {
+			schemas[k] = s
+		}
+	}
+
+	names := map[string]bool{}
+	for _, n := range functionNames {
+		names[n] = true
+	}
+
+	var diags []Diagnostic
+	for _, d := range docs {
+		if kind(d.Node) != "Composition" {
+			continue
+		}
+		spec := child(d.Node, "spec")
+		schema := schemas[scalar(child(spec, "compositeTypeRef", "apiVersion"))+"/"+scalar(child(spec, "compositeTypeRef", "kind"))]
+		for _, step := range elements(child(spec, "pipeline")) {
+			input := child(step, "input")
+			if !names[scalar(child(step, "functionRef", "name"))] && !strings.HasPrefix(scalar(child(input, "apiVersion")), inputGroup+"/") {
+				continue
+			}
+			for _, msg := range lintStep(reg, step, schema) {
+				diags = append(diags, Diagnostic{
+					File:    d.File,
+					Line:    msg.node.Line,
+					Column:  msg.node.Column,
+					Message: fmt.Sprintf("step %s: %s", scalar(child(step, "step")), msg.message),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].File != diags[j].File {
+			return diags[i].File < diags[j].File
+		}
+		return diags[i].Line < diags[j].Line
+	})
+	return diags
+}
+
+// A lintMessage is a problem found at a node of a pipeline step input.
+type lintMessage struct {
+	node    *yaml.Node
+	message string
+}
+
+// lintStep validates the input of the supplied pipeline step.
+func lintStep(reg *operation.Registry, step *yaml.Node, schema map[string]any) []lintMessage {
+	input := child(step, "input")
+	if input == nil {
+		return []lintMessage{{node: step, message: "input is required"}}
+	}
+
+	var v any
+	if err := input.Decode(&v); err != nil {
+		return []lintMessage{{node: input, message: err.Error()}}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return []lintMessage{{node: input, message: err.Error()}}
+	}
+	p := &v1beta1.Parameters{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(p); err != nil {
+		return []lintMessage{{node: input, message: errors.Wrap(err, "cannot decode input as Parameters").Error()}}
+	}
+
+	var msgs []lintMessage
+	for _, e := range validateParameters(reg, p, nil, nil) {
+		msgs = append(msgs, lintMessage{node: lookup(input, strings.TrimPrefix(e.Field, "parameters")), message: e.Error()})
+	}
+	if schema == nil {
+		return msgs
+	}
+
+	for _, f := range []struct{ name, path string }{
+		{"cidrFuncField", p.CidrFuncField},
+		{"prefixField", p.PrefixField},
+		{"hostNumField", p.HostNumField},
+		{"newBitsField", p.NewBitsField},
+		{"netNumField", p.NetNumField},
+		{"netNumCountField", p.NetNumCountField},
+		{"netNumItemsField", p.NetNumItemsField},
+		{"subnetItemsField", p.SubnetItemsField},
+		{"offsetField", p.OffsetField},
+		{"multiPrefixField", p.MultiPrefixField},
+		{"hashKeyField", p.HashKeyField},
+		{"usedCIDRsField", p.UsedCIDRsField},
+		{"reservedNetNumsField", p.ReservedNetNumsField},
+		{"seedField", p.SeedField},
+		{"ipv6PrefixField", p.IPv6PrefixField},
+		{"ipv6NewBitsField", p.IPv6NewBitsField},
+		{"macField", p.MACField},
+		{"ipv4AddressField", p.IPv4AddressField},
+		{"k8sNetworkField", p.K8sNetworkField},
+		{"secondaryRangesField", p.SecondaryRangesField},
+		{"eksInstanceTypesField", p.EKSInstanceTypesField},
+		{"layoutField", p.LayoutField},
+	} {
+		// Prefixes may also be read from the desired state or the context,
+		// neither of which has a schema.
+		if f.path == "" || strings.HasPrefix(f.path, "desired.") || strings.HasPrefix(f.path, "context.") {
+			continue
+		}
+		segments, err := fieldpath.Parse(f.path)
+		if err != nil {
+			msgs = append(msgs, lintMessage{node: lookup(input, f.name), message: fmt.Sprintf("parameters.%s: invalid field path %q: %s", f.name, f.path, err)})
+			continue
+		}
+		if !schemaHas(schema, segments) {
+			msgs = append(msgs, lintMessage{node: lookup(input, f.name), message: fmt.Sprintf("parameters.%s: field %s is not defined in the schema of the composite resource", f.name, f.path)})
+		}
+	}
+	return msgs
+}
+
+// xrdSchemas returns the OpenAPI schemas of every version of the supplied
+// CompositeResourceDefinition, keyed by apiVersion and kind.
+func xrdSchemas(xrd *yaml.Node) map[string]map[string]any {
+	spec := child(xrd, "spec")
+	group := scalar(child(spec, "group"))
+	kind := scalar(child(spec, "names", "kind"))
+
+	schemas := map[string]map[string]any{}
+	for _, v := range elements(child(spec, "versions")) {
+		n := child(v, "schema", "openAPIV3Schema")
+		if n == nil {
+			continue
+		}
+		var s map[string]any
+		if err := n.Decode(&s); err != nil {
+			continue
+		}
+		schemas[group+"/"+scalar(child(v, "name"))+"/"+kind] = s
+	}
+	return schemas
+}
+
+// schemaHas returns true if the field path described by the supplied segments
+// is defined by the supplied OpenAPI schema. Metadata and fields that preserve
+// unknown fields are always considered defined.
+func schemaHas(schema map[string]any, segments fieldpath.Segments) bool {
+	if len(segments) > 0 && segments[0].Field == "metadata" {
+		return true
+	}
+
+	s := schema
+	for _, seg := range segments {
+		if preserve, _ := s["x-kubernetes-preserve-unknown-fields"].(bool); preserve {
+			return true
+		}
+
+		switch seg.Type {
+		case fieldpath.SegmentField:
+			props, _ := s["properties"].(map[string]any)
+			if p, ok := props[seg.Field].(map[string]any); ok {
+				s = p
+				continue
+			}
+			switch ap := s["additionalProperties"].(type) {
+			case bool:
+				return ap
+			case map[string]any:
+				s = ap
+				continue
+			}
+			return false
+		case fieldpath.SegmentIndex:
+			items, ok := s["items"].(map[string]any)
+			if !ok {
+				return false
+			}
+			s = items
+		}
+	}
+	return true
+}
+
+// kind returns the kind of the supplied object.
+func kind(n *yaml.Node) string {
+	return scalar(child(n, "kind"))
+}
+
+// child returns the node at the supplied keys of a mapping node, or nil if
+// there is none.
+func child(n *yaml.Node, keys ...string) *yaml.Node {
+	for _, k := range keys {
+		if n == nil || n.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == k {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		n = next
+	}
+	return n
+}
+
+// elements returns the elements of the supplied sequence node, or nil.
+func elements(n *yaml.Node) []*yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
+
+// scalar returns the value of the supplied scalar node, or an empty string.
+func scalar(n *yaml.Node) string {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
+
+// lookup returns the node at the supplied field path below n. It returns the
+// deepest node that exists if the path cannot be followed to its end.
+func lookup(n *yaml.Node, path string) *yaml.Node {
+	segments, err := fieldpath.Parse(strings.TrimPrefix(path, "."))
+	if err != nil {
+		return n
+	}
+	for _, seg := range segments {
+		var next *yaml.Node
+		switch {
+		case seg.Type == fieldpath.SegmentField:
+			next = child(n, seg.Field)
+		case seg.Type == fieldpath.SegmentIndex && n.Kind == yaml.SequenceNode && int(seg.Index) < len(n.Content):
+			next = n.Content[seg.Index]
+		}
+		if next == nil {
+			return n
+		}
+		n = next
+	}
+	return n
+}
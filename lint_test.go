@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func TestLint(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		files  []string
+		want   []Diagnostic
+	}{
+		"Examples": {
+			reason: "should find no problems in the example Composition",
+			files:  []string{"apis/definition.yaml", "apis/composition.yaml", "apis/composition-pipeline.yaml"},
+		},
+		"Problems": {
+			reason: "should report invalid parameters and fields missing from the XRD schema at their line",
+			files:  []string{"testdata/lint/composition.yaml"},
+			want: []Diagnostic{
+				{
+					File:    "testdata/lint/composition.yaml",
+					Line:    46,
+					Column:  27,
+					Message: "step subnets: parameters.netNumItemsField: field spec.zones is not defined in the schema of the composite resource",
+				},
+				{
+					File:    "testdata/lint/composition.yaml",
+					Line:    49,
+					Column:  13,
+					Message: "step subnets: parameters.newBits[1]: Invalid value: 129: newBits must be between 0 and 128",
+				},
+				{
+					File:    "testdata/lint/composition.yaml",
+					Line:    57,
+					Column:  17,
+					Message: `step host: parameters.prefix: Invalid value: "10.0.0.0/33": invalid CIDR prefix address`,
+				},
+				{
+					File:    "testdata/lint/composition.yaml",
+					Line:    65,
+					Column:  19,
+					Message: `step unknown: parameters.cidrFunc: Unsupported value: "cidrsubnetz": supported values: ` + supportedValues(),
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var docs []Document
+			for _, f := range tc.files {
+				d, err := ReadDocuments(f)
+				if err != nil {
+					t.Fatalf("ReadDocuments(...): %v", err)
+				}
+				docs = append(docs, d...)
+			}
+			got := Lint(operation.DefaultRegistry, []string{"function-cidr", "upbound-function-cidr"}, docs)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nLint(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
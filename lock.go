@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/crossplane/function-sdk-go/resource"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+)
+
+// reasonLocked is the reason of results caused by a calculation that differs
+// from a locked output.
+const reasonLocked = "Locked"
+
+// A LockedError is returned if a calculation differs from the output that is
+// already published at the locked outputField.
+type LockedError struct {
+	Field    string
+	Locked   any
+	Computed any
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("outputField %s is locked to %v, refusing to change it to %v", e.Field, e.Locked, e.Computed)
+}
+
+// Reason returns the reason of the LockedError.
+func (e *LockedError) Reason() string {
+	return reasonLocked
+}
+
+// validateLock validates the lock parameters.
+func validateLock(p *v1beta1.Parameters) field.ErrorList {
+	switch p.LockPolicy {
+	case "", v1beta1.LockPolicyWarning, v1beta1.LockPolicyFatal:
+		return nil
+	default:
+		return field.ErrorList{field.NotSupported(field.NewPath("parameters").Child("lockPolicy"), p.LockPolicy, []string{string(v1beta1.LockPolicyWarning), string(v1beta1.LockPolicyFatal)})}
+	}
+}
+
+// CheckLock compares the supplied output with the value already published at
+// the supplied field of the observed composite resource. It returns a
+// LockedError holding the published value if the two differ, and nil if they
+// are equal or nothing is published yet.
+func CheckLock(oxr *resource.Composite, path string, output any) error {
+	locked, err := oxr.Resource.GetValue(path)
+	if fieldpath.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "cannot get locked outputField %s", path)
+	}
+
+	// Round-trip the output through JSON so it is made of the same types as
+	// the observed composite resource.
+	b, err := json.Marshal(output)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal output")
+	}
+	var computed any
+	if err := json.Unmarshal(b, &computed); err != nil {
+		return errors.Wrap(err, "cannot unmarshal output")
+	}
+
+	if reflect.DeepEqual(locked, computed) {
+		return nil
+	}
+	return &LockedError{Field: path, Locked: locked, Computed: computed}
+}
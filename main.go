@@ -2,23 +2,40 @@
 package main
 
 import (
+	"context"
+
 	"github.com/alecthomas/kong"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
 
 	"github.com/crossplane/function-sdk-go"
 )
 
 // CLI of this Function.
 type CLI struct {
+	Serve  ServeCmd  `cmd:"" default:"withargs" help:"Serve the Composition Function over gRPC. This is the default command."`
+	Calc   CalcCmd   `cmd:"" help:"Run a cidrFunc locally and print its result."`
+	Render RenderCmd `cmd:"" help:"Run the function in-process against an XR and print the desired XR and results."`
+	Lint   LintCmd   `cmd:"" help:"Statically validate the Compositions that use this function."`
+}
+
+// ServeCmd serves the Function.
+type ServeCmd struct {
 	Debug bool `short:"d" help:"Emit debug logs in addition to info logs."`
 
 	Network     string `help:"Network on which to listen for gRPC connections." default:"tcp"`
 	Address     string `help:"Address at which to listen for gRPC connections." default:":9443"`
 	TLSCertsDir string `help:"Server certs directory (tls.key, tls.crt) and the CA used to verify client certificates (ca.crt)" env:"TLS_SERVER_CERTS_DIR"`
 	Insecure    bool   `help:"Run without mTLS credentials. If you supply this flag --tls-server-certs-dir will be ignored."`
+
+	MetricsAddress string `help:"Address at which to serve Prometheus metrics. Metrics are disabled if empty." default:":8080" env:"METRICS_ADDRESS"`
+
+	OTLPEndpoint string `help:"OTLP gRPC endpoint to export traces to, e.g. localhost:4317. Tracing is disabled if empty." env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTLPInsecure bool   `help:"Export traces to the OTLP endpoint without TLS." env:"OTEL_EXPORTER_OTLP_INSECURE"`
 }
 
 // Run this Function.
-func (c *CLI) Run() error {
+func (c *ServeCmd) Run() error {
 	log, err := function.NewLogger(c.Debug)
 	if err != nil {
 		return err
@@ -26,10 +43,26 @@ func (c *CLI) Run() error {
 
 	log.Info("Running Crossplane CIDR Composition Function.")
 
-	return function.Serve(&Function{log: log},
+	if c.OTLPEndpoint != "" {
+		tp, err := NewTracerProvider(context.Background(), c.OTLPEndpoint, c.OTLPInsecure)
+		if err != nil {
+			return err
+		}
+		defer tp.Shutdown(context.Background()) //nolint:errcheck // Nothing to do if flushing spans fails on exit.
+		otel.SetTracerProvider(tp)
+	}
+
+	f := &Function{log: log}
+	if c.MetricsAddress != "" {
+		f.metrics = NewMetrics()
+		prometheus.MustRegister(f.metrics)
+	}
+
+	return function.Serve(f,
 		function.Listen(c.Network, c.Address),
 		function.MTLSCertificates(c.TLSCertsDir),
-		function.Insecure(c.Insecure))
+		function.Insecure(c.Insecure),
+		function.WithMetricsServer(c.MetricsAddress))
 }
 
 func main() {
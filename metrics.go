@@ -0,0 +1,146 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+const metricsNamespace = "function_cidr"
+
+// unknownCidrFunc is the cidr_func label of runs of cidrFuncs that are not
+// supported. It keeps the cardinality of the metrics bounded.
+const unknownCidrFunc = "unknown"
+
+// Metrics records Prometheus metrics about the cidrFuncs run by the Function.
+// A nil *Metrics records nothing.
+type Metrics struct {
+	invocations     *prometheus.CounterVec
+	fatalResults    *prometheus.CounterVec
+	duration        *prometheus.HistogramVec
+	cidrsProduced   *prometheus.CounterVec
+	poolUtilization *prometheus.GaugeVec
+}
+
+// NewMetrics returns Metrics that are yet to be registered.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		invocations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "invocations_total",
+			Help:      "Number of times the function was run, by cidrFunc.",
+		}, []string{"cidr_func"}),
+		fatalResults: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "fatal_results_total",
+			Help:      "Number of fatal results returned by the function, by cidrFunc and reason.",
+		}, []string{"cidr_func", "reason"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "run_duration_seconds",
+			Help:      "Time it took to run the function, by cidrFunc.",
+			Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1},
+		}, []string{"cidr_func"}),
+		cidrsProduced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "cidrs_produced_total",
+			Help:      "Number of CIDRs and addresses written to the composite resource, by cidrFunc.",
+		}, []string{"cidr_func"}),
+		poolUtilization: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "pool_utilization_ratio",
+			Help:      "Fraction of the addresses of a pool allocated by the last run of an allocating cidrFunc, by cidrFunc and pool.",
+		}, []string{"cidr_func", "pool"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.invocations.Describe(ch)
+	m.fatalResults.Describe(ch)
+	m.duration.Describe(ch)
+	m.cidrsProduced.Describe(ch)
+	m.poolUtilization.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.invocations.Collect(ch)
+	m.fatalResults.Collect(ch)
+	m.duration.Collect(ch)
+	m.cidrsProduced.Collect(ch)
+	m.poolUtilization.Collect(ch)
+}
+
+// ObserveRun records a run of the supplied cidrFunc that returned the supplied
+// response after running for d. Runs of cidrFuncs that are not supported by
+// the supplied registry are recorded as runs of the unknown cidrFunc.
+func (m *Metrics) ObserveRun(reg *operation.Registry, cidrFunc string, rsp *fnv1.RunFunctionResponse, d time.Duration) {
+	if m == nil {
+		return
+	}
+	if !reg.Supports(cidrFunc) {
+		cidrFunc = unknownCidrFunc
+	}
+	m.invocations.WithLabelValues(cidrFunc).Inc()
+	m.duration.WithLabelValues(cidrFunc).Observe(d.Seconds())
+	for _, r := range rsp.GetResults() {
+		if r.GetSeverity() == fnv1.Severity_SEVERITY_FATAL {
+			m.fatalResults.WithLabelValues(cidrFunc, r.GetReason()).Inc()
+		}
+	}
+}
+
+// ObserveOutput records the output of a successful run of the supplied
+// cidrFunc.
+func (m *Metrics) ObserveOutput(cidrFunc string, op operation.CidrOperation, value any) {
+	if m == nil {
+		return
+	}
+	m.cidrsProduced.WithLabelValues(cidrFunc).Add(float64(countCIDRs(value)))
+
+	a, ok := op.(operation.Allocator)
+	if !ok {
+		return
+	}
+	for pool, subnets := range a.Allocations() {
+		m.poolUtilization.WithLabelValues(cidrFunc, pool.String()).Set(cidr.Utilization(pool, subnets...))
+	}
+}
+
+// countCIDRs returns the number of CIDRs or addresses in the rendered output
+// of a CidrOperation.
+func countCIDRs(value any) int {
+	switch v := value.(type) {
+	case string:
+		return 1
+	case []string:
+		return len(v)
+	case map[string]string:
+		return len(v)
+	case map[string][]string:
+		n := 0
+		for _, s := range v {
+			n += len(s)
+		}
+		return n
+	case []any:
+		n := 0
+		for _, e := range v {
+			n += countCIDRs(e)
+		}
+		return n
+	case map[string]any:
+		n := 0
+		for _, e := range v {
+			n += countCIDRs(e)
+		}
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	f := &Function{log: logging.NewNopLogger(), metrics: m}
+
+	run := func(input string) {
+		t.Helper()
+		req := &fnv1.RunFunctionRequest{
+			Input: resource.MustStructJSON(input),
+			Observed: &fnv1.State{
+				Composite: &fnv1.Resource{
+					Resource: resource.MustStructJSON(`{"apiVersion": "example.crossplane.io/v1", "kind": "XNetwork"}`),
+				},
+			},
+		}
+		if _, err := f.RunFunction(context.Background(), req); err != nil {
+			t.Fatalf("RunFunction(...): %v", err)
+		}
+	}
+
+	run(`{"apiVersion": "cidr.fn.crossplane.io/v1beta1", "kind": "Parameters", "cidrFunc": "cidrsubnets", "prefix": "10.0.0.0/16", "newBits": [2, 2, 1]}`)
+	run(`{"apiVersion": "cidr.fn.crossplane.io/v1beta1", "kind": "Parameters", "cidrFunc": "cidrsubnets", "prefix": "10.0.0.0/24", "newBits": [1, 1, 1]}`)
+	run(`{"apiVersion": "cidr.fn.crossplane.io/v1beta1", "kind": "Parameters", "cidrFunc": "cidrhost", "prefix": "10.0.0.0/24", "hostNum": 5}`)
+	run(`{"apiVersion": "cidr.fn.crossplane.io/v1beta1", "kind": "Parameters", "cidrFunc": "cidrsubnetloop", "prefix": "10.1.0.0/16", "newBits": [2], "netNumCount": 1, "usedCIDRs": ["10.1.0.0/17", "10.1.0.0/18"]}`)
+	run(`{"apiVersion": "cidr.fn.crossplane.io/v1beta1", "kind": "Parameters", "cidrFunc": "does-not-exist"}`)
+
+	want := `
+# HELP function_cidr_cidrs_produced_total Number of CIDRs and addresses written to the composite resource, by cidrFunc.
+# TYPE function_cidr_cidrs_produced_total counter
+function_cidr_cidrs_produced_total{cidr_func="cidrhost"} 1
+function_cidr_cidrs_produced_total{cidr_func="cidrsubnetloop"} 1
+function_cidr_cidrs_produced_total{cidr_func="cidrsubnets"} 3
+# HELP function_cidr_fatal_results_total Number of fatal results returned by the function, by cidrFunc and reason.
+# TYPE function_cidr_fatal_results_total counter
+function_cidr_fatal_results_total{cidr_func="cidrsubnets",reason="PoolExhausted"} 1
+function_cidr_fatal_results_total{cidr_func="unknown",reason="InvalidInput"} 1
+# HELP function_cidr_invocations_total Number of times the function was run, by cidrFunc.
+# TYPE function_cidr_invocations_total counter
+function_cidr_invocations_total{cidr_func="cidrhost"} 1
+function_cidr_invocations_total{cidr_func="cidrsubnetloop"} 1
+function_cidr_invocations_total{cidr_func="cidrsubnets"} 2
+function_cidr_invocations_total{cidr_func="unknown"} 1
+# HELP function_cidr_pool_utilization_ratio Fraction of the addresses of a pool allocated by the last run of an allocating cidrFunc, by cidrFunc and pool.
+# TYPE function_cidr_pool_utilization_ratio gauge
+function_cidr_pool_utilization_ratio{cidr_func="cidrsubnetloop",pool="10.1.0.0/16"} 0.75
+function_cidr_pool_utilization_ratio{cidr_func="cidrsubnets",pool="10.0.0.0/16"} 1
+`
+	names := []string{
+		"function_cidr_cidrs_produced_total",
+		"function_cidr_fatal_results_total",
+		"function_cidr_invocations_total",
+		"function_cidr_pool_utilization_ratio",
+	}
+	if err := testutil.CollectAndCompare(m, strings.NewReader(want), names...); err != nil {
+		t.Errorf("CollectAndCompare(...): %v", err)
+	}
+	if n := testutil.CollectAndCount(m, "function_cidr_run_duration_seconds"); n != 4 {
+		t.Errorf("CollectAndCount(...): want 4 run duration series, got %d", n)
+	}
+}
+
+func TestMetricsCustomRegistry(t *testing.T) {
+	reg := operation.NewRegistry()
+	reg.MustRegister("prefixlength", func() operation.CidrOperation { return &prefixLengthOperation{} })
+
+	m := NewMetrics()
+	f := &Function{log: logging.NewNopLogger(), registry: reg, metrics: m}
+
+	req := &fnv1.RunFunctionRequest{
+		Input: resource.MustStructJSON(`{"apiVersion": "cidr.fn.crossplane.io/v1beta1", "kind": "Parameters", "cidrFunc": "prefixlength", "prefix": "10.0.0.0/16"}`),
+	}
+	if _, err := f.RunFunction(context.Background(), req); err != nil {
+		t.Fatalf("RunFunction(...): %v", err)
+	}
+
+	want := `
+# HELP function_cidr_invocations_total Number of times the function was run, by cidrFunc.
+# TYPE function_cidr_invocations_total counter
+function_cidr_invocations_total{cidr_func="prefixlength"} 1
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(want), "function_cidr_invocations_total"); err != nil {
+		t.Errorf("CollectAndCompare(...): %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/netip"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/crossplane/function-sdk-go/resource"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+func init() {
+	operation.DefaultRegistry.MustRegister("multiprefixloop", func() operation.CidrOperation { return &multiPrefixLoopOperation{} })
+}
+
+// ValidateMultiCidrPrefixParameter validates the Parameters object
+// in the context of multiprefixloop
+func ValidateMultiCidrPrefixParameter(p *v1beta1.Parameters, oxr *resource.Composite) field.ErrorList {
+	path := field.NewPath("parameters")
+	if len(p.MultiPrefix) > 0 && len(p.MultiPrefixField) > 0 {
+		return field.ErrorList{field.Forbidden(path.Child("multiPrefixField"), "specify only one of multiPrefix or multiPrefixField to avoid ambiguous function input")}
+	}
+
+	if len(p.MultiPrefix) == 0 && p.MultiPrefixField == "" {
+		return field.ErrorList{field.Required(path.Child("multiPrefix"), "either multiPrefix or multiPrefixField function input is required")}
+	}
+
+	multiPrefixes := p.MultiPrefix
+	mpPath := path.Child("multiPrefix")
+	if len(p.MultiPrefix) == 0 {
+		if oxr == nil {
+			return nil
+		}
+		if err := oxr.Resource.GetValueInto(p.MultiPrefixField, &multiPrefixes); err != nil {
+			return field.ErrorList{field.Invalid(path.Child("multiPrefixField"), p.MultiPrefixField, "cannot get multiPrefixes at multiPrefixField")}
+		}
+		mpPath = path.Child("multiPrefixField")
+	}
+
+	var errs field.ErrorList
+	keys := make(map[string]bool, len(multiPrefixes))
+	for i, mp := range multiPrefixes {
+		if _, err := cidr.ParsePrefix(mp.Prefix); err != nil {
+			errs = append(errs, field.Invalid(mpPath.Index(i).Child("prefix"), mp.Prefix, "invalid CIDR prefix address"))
+		}
+
+		if len(mp.NewBits) == 0 {
+			errs = append(errs, field.Required(mpPath.Index(i).Child("newBits"), "newBits is required for each prefix in multiPrefix"))
+		}
+		errs = append(errs, validateNewBits(mpPath.Index(i).Child("newBits"), mp.NewBits, 1, cidr.Bits32)...)
+
+		key, keyPath := multiPrefixKey(mp), mpPath.Index(i).Child("prefix")
+		if mp.Name != "" {
+			keyPath = mpPath.Index(i).Child("name")
+		}
+		if keys[key] {
+			errs = append(errs, field.Duplicate(keyPath, key))
+		}
+		keys[key] = true
+
+		if len(mp.Names) > 0 && len(mp.Names) != len(mp.NewBits) {
+			errs = append(errs, field.Invalid(mpPath.Index(i).Child("names"), mp.Names, "names requires one name for each newBits element"))
+		}
+		names := make(map[string]bool, len(mp.Names))
+		for j, name := range mp.Names {
+			switch {
+			case name == "":
+				errs = append(errs, field.Required(mpPath.Index(i).Child("names").Index(j), "names must not be empty"))
+			case names[name]:
+				errs = append(errs, field.Duplicate(mpPath.Index(i).Child("names").Index(j), name))
+			}
+			names[name] = true
+		}
+	}
+
+	return errs
+}
+
+// multiPrefixKey returns the key of the subnets of the supplied entry in the
+// output of multiprefixloop.
+func multiPrefixKey(mp v1beta1.MultiPrefix) string {
+	if mp.Name != "" {
+		return mp.Name
+	}
+	return mp.Prefix
+}
+
+// multiPrefixLoopOperation is a convenience wrapper around cidrsubnets that
+// loops over a range of prefixes to create a list of subnets for each prefix.
+// The subnets are keyed by the name or the prefix of their entry, and are
+// themselves keyed by name if the entry names them.
+type multiPrefixLoopOperation struct {
+	multiPrefixes []v1beta1.MultiPrefix
+	subnetsByKey  map[string]any
+	allocations   map[netip.Prefix][]netip.Prefix
+	newBits       map[string][]int
+}
+
+func (o *multiPrefixLoopOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	return ValidateMultiCidrPrefixParameter(p, r.Composite())
+}
+
+func (o *multiPrefixLoopOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	o.multiPrefixes = p.MultiPrefix
+	return r.Into("multiPrefix", p.MultiPrefixField, &o.multiPrefixes)
+}
+
+func (o *multiPrefixLoopOperation) Compute() error {
+	o.subnetsByKey = make(map[string]any)
+	o.allocations = make(map[netip.Prefix][]netip.Prefix)
+	o.newBits = make(map[string][]int)
+
+	for _, multiPrefix := range o.multiPrefixes {
+		prefix := multiPrefix.Prefix
+		if len(prefix) == 0 {
+			continue
+		}
+
+		newBits := multiPrefix.NewBits
+		if len(newBits) == 0 {
+			continue
+		}
+
+		if multiPrefix.Offset > 0 {
+			newBits = append([]int{multiPrefix.Offset}, newBits...)
+		}
+
+		key := multiPrefixKey(multiPrefix)
+		o.newBits[key] = newBits
+
+		p, err := cidr.ParsePrefix(prefix)
+		if err != nil {
+			return err
+		}
+		subnets, err := cidr.Subnets(p, newBits...)
+		if err != nil {
+			return err
+		}
+
+		if multiPrefix.Offset > 0 {
+			subnets = subnets[1:]
+		}
+		o.allocations[p] = append(o.allocations[p], subnets...)
+
+		if len(multiPrefix.Names) == 0 {
+			o.subnetsByKey[key] = prefixStrings(subnets)
+			continue
+		}
+		named := make(map[string]string, len(subnets))
+		for i, s := range subnets {
+			named[multiPrefix.Names[i]] = s.String()
+		}
+		o.subnetsByKey[key] = named
+	}
+	return nil
+}
+
+func (o *multiPrefixLoopOperation) Render() (any, error) {
+	return o.subnetsByKey, nil
+}
+
+func (o *multiPrefixLoopOperation) Intermediates() map[string]any {
+	return map[string]any{"newBits": o.newBits}
+}
+
+func (o *multiPrefixLoopOperation) Allocations() map[netip.Prefix][]netip.Prefix {
+	return o.allocations
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/netip"
+
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+// SupportedCidrFuncs returns the sorted names of all built-in cidrFuncs.
+func SupportedCidrFuncs() []string {
+	return operation.DefaultRegistry.Names()
+}
+
+// prefixStrings returns the string representation of the supplied prefixes.
+func prefixStrings(prefixes []netip.Prefix) []string {
+	if len(prefixes) == 0 {
+		return nil
+	}
+	s := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		s[i] = p.String()
+	}
+	return s
+}
+
+// parsePrefixes parses the supplied CIDR prefixes.
+func parsePrefixes(s []string) ([]netip.Prefix, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	prefixes := make([]netip.Prefix, len(s))
+	for i, p := range s {
+		var err error
+		if prefixes[i], err = cidr.ParsePrefix(p); err != nil {
+			return nil, err
+		}
+	}
+	return prefixes, nil
+}
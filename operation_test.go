@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+// prefixLengthOperation is a custom operation that returns the length of the
+// prefix.
+type prefixLengthOperation struct {
+	prefix string
+	length int
+}
+
+func (o *prefixLengthOperation) Validate(p *v1beta1.Parameters, r *operation.Resolver) field.ErrorList {
+	return ValidatePrefixParameter(p.Prefix, p.PrefixField, r.Composite(), r.Request())
+}
+
+func (o *prefixLengthOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	o.prefix, err = r.Prefix(p.Prefix, p.PrefixField)
+	return err
+}
+
+func (o *prefixLengthOperation) Compute() error {
+	prefix, err := cidr.ParsePrefix(o.prefix)
+	if err != nil {
+		return err
+	}
+	o.length = prefix.Bits()
+	return nil
+}
+
+func (o *prefixLengthOperation) Render() (any, error) {
+	return int64(o.length), nil
+}
+
+func TestRunFunctionCustomOperation(t *testing.T) {
+	reg := operation.NewRegistry()
+	reg.MustRegister("prefixlength", func() operation.CidrOperation { return &prefixLengthOperation{} })
+
+	req := &fnv1.RunFunctionRequest{
+		Input: resource.MustStructJSON(`{"cidrFunc": "prefixlength", "prefix": "10.0.0.0/16"}`),
+	}
+	want := &fnv1.RunFunctionResponse{
+		Desired: &fnv1.State{
+			Composite: &fnv1.Resource{
+				Resource: resource.MustStructJSON(`{"apiVersion":"","kind":"","status": {"atFunction": {"cidr": 16}}}`),
+			},
+		},
+		Conditions: allocated("prefixlength", "status.atFunction.cidr"),
+		Meta:       responseMeta(),
+	}
+
+	f := &Function{log: logging.NewNopLogger(), registry: reg}
+	rsp, err := f.RunFunction(context.Background(), req)
+	if err != nil {
+		t.Fatalf("f.RunFunction(...): unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, rsp, protocmp.Transform()); diff != "" {
+		t.Errorf("f.RunFunction(...): -want rsp, +got rsp:\n%s", diff)
+	}
+}
@@ -0,0 +1,143 @@
+// Package cidr implements Classless Inter-Domain Routing calculations that are
+// compatible with the HashiCorp IP network functions, on top of net/netip.
+//
+// All functions operate on masked prefixes and never allocate except when
+// returning slices of results.
+package cidr
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"net/netip"
+)
+
+// Address lengths of the supported address families.
+const (
+	Bits32  = 32
+	Bits128 = 128
+)
+
+// ParsePrefix parses s as a CIDR prefix and returns it masked, i.e. with all
+// host bits set to zero.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, &InvalidPrefixError{Prefix: s, Err: err}
+	}
+	return p.Masked(), nil
+}
+
+// MustParsePrefix is like ParsePrefix but panics if s cannot be parsed.
+func MustParsePrefix(s string) netip.Prefix {
+	p, err := ParsePrefix(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Netmask returns the netmask of the supplied prefix as an address of the
+// same family, e.g. 255.255.255.0 for a /24 IPv4 prefix.
+func Netmask(prefix netip.Prefix) netip.Addr {
+	hostLen := prefix.Addr().BitLen() - prefix.Bits()
+	return toAddr(hostMask(hostLen).not(prefix.Addr().BitLen()), prefix.Addr())
+}
+
+// Protocol returns the name of the address family of the supplied prefix.
+func Protocol(prefix netip.Prefix) string {
+	switch prefix.Addr().BitLen() {
+	case Bits32:
+		return "IPv4"
+	case Bits128:
+		return "IPv6"
+	}
+	return "IP"
+}
+
+// FirstAddr returns the first address of the supplied prefix.
+func FirstAddr(prefix netip.Prefix) netip.Addr {
+	return prefix.Masked().Addr()
+}
+
+// LastAddr returns the last address of the supplied prefix.
+func LastAddr(prefix netip.Prefix) netip.Addr {
+	p := prefix.Masked()
+	hostLen := p.Addr().BitLen() - p.Bits()
+	return toAddr(fromAddr(p.Addr()).or(hostMask(hostLen)), p.Addr())
+}
+
+// uint128 is an unsigned 128 bit integer used for address arithmetic.
+type uint128 struct {
+	hi, lo uint64
+}
+
+// fromAddr returns the integer value of the supplied address. IPv4 addresses
+// occupy the lower 32 bits.
+func fromAddr(a netip.Addr) uint128 {
+	if a.Is4() {
+		b := a.As4()
+		return uint128{lo: uint64(binary.BigEndian.Uint32(b[:]))}
+	}
+	b := a.As16()
+	return uint128{hi: binary.BigEndian.Uint64(b[:8]), lo: binary.BigEndian.Uint64(b[8:])}
+}
+
+// toAddr returns the address with the integer value u, in the same family as
+// the template address.
+func toAddr(u uint128, template netip.Addr) netip.Addr {
+	if template.Is4() {
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(u.lo))
+		return netip.AddrFrom4(b)
+	}
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], u.hi)
+	binary.BigEndian.PutUint64(b[8:], u.lo)
+	return netip.AddrFrom16(b)
+}
+
+// hostMask returns an integer with the lowest n bits set.
+func hostMask(n int) uint128 {
+	switch {
+	case n <= 0:
+		return uint128{}
+	case n < 64:
+		return uint128{lo: 1<<uint(n) - 1}
+	case n < 128:
+		return uint128{hi: 1<<uint(n-64) - 1, lo: ^uint64(0)}
+	}
+	return uint128{hi: ^uint64(0), lo: ^uint64(0)}
+}
+
+// not returns the bitwise complement of u within the lowest n bits.
+func (u uint128) not(n int) uint128 {
+	m := hostMask(n)
+	return uint128{hi: ^u.hi & m.hi, lo: ^u.lo & m.lo}
+}
+
+func (u uint128) or(v uint128) uint128 {
+	return uint128{hi: u.hi | v.hi, lo: u.lo | v.lo}
+}
+
+func (u uint128) add(v uint64) uint128 {
+	lo, carry := bits.Add64(u.lo, v, 0)
+	return uint128{hi: u.hi + carry, lo: lo}
+}
+
+func (u uint128) sub(v uint64) uint128 {
+	lo, borrow := bits.Sub64(u.lo, v, 0)
+	return uint128{hi: u.hi - borrow, lo: lo}
+}
+
+// shl returns u shifted left by n bits.
+func (u uint128) shl(n int) uint128 {
+	switch {
+	case n <= 0:
+		return u
+	case n >= 128:
+		return uint128{}
+	case n >= 64:
+		return uint128{hi: u.lo << uint(n-64)}
+	}
+	return uint128{hi: u.hi<<uint(n) | u.lo>>uint(64-n), lo: u.lo << uint(n)}
+}
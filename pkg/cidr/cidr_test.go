@@ -0,0 +1,653 @@
+package cidr
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+var cmpNetip = cmp.Comparer(func(a, b netip.Prefix) bool { return a == b })
+
+func TestHost(t *testing.T) {
+	type args struct {
+		prefix  netip.Prefix
+		hostNum int64
+	}
+	type want struct {
+		addr netip.Addr
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"IPv4": {
+			reason: "should return the hostNum'th address",
+			args:   args{prefix: MustParsePrefix("10.12.112.0/20"), hostNum: 268},
+			want:   want{addr: netip.MustParseAddr("10.12.113.12")},
+		},
+		"IPv4Negative": {
+			reason: "should count backwards from the end of the prefix",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/24"), hostNum: -1},
+			want:   want{addr: netip.MustParseAddr("10.0.0.255")},
+		},
+		"IPv6": {
+			reason: "should return the hostNum'th address of an IPv6 prefix",
+			args:   args{prefix: MustParsePrefix("fd00:fd12:3456:7890::/56"), hostNum: 34},
+			want:   want{addr: netip.MustParseAddr("fd00:fd12:3456:7800::22")},
+		},
+		"IPv6Negative": {
+			reason: "should count backwards from the end of an IPv6 prefix",
+			args:   args{prefix: MustParsePrefix("fd00::/64"), hostNum: -2},
+			want:   want{addr: netip.MustParseAddr("fd00::ffff:ffff:ffff:fffe")},
+		},
+		"OutOfRange": {
+			reason: "should fail when the hostNum does not fit into the prefix",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/24"), hostNum: 256},
+			want:   want{err: &HostNumOutOfRangeError{Prefix: MustParsePrefix("10.0.0.0/24"), HostNum: 256}},
+		},
+		"NegativeOutOfRange": {
+			reason: "should fail when a negative hostNum does not fit into the prefix",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/24"), hostNum: -257},
+			want:   want{err: &HostNumOutOfRangeError{Prefix: MustParsePrefix("10.0.0.0/24"), HostNum: -257}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			addr, err := Host(tc.args.prefix, tc.args.hostNum)
+			if diff := cmp.Diff(tc.want.addr, addr, cmp.Comparer(func(a, b netip.Addr) bool { return a == b })); diff != "" {
+				t.Errorf("%s\nHost(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, cmpNetip); diff != "" {
+				t.Errorf("%s\nHost(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNetmask(t *testing.T) {
+	cases := map[string]struct {
+		prefix string
+		want   string
+	}{
+		"IPv4":     {prefix: "172.16.0.0/12", want: "255.240.0.0"},
+		"IPv4Host": {prefix: "10.0.0.1/32", want: "255.255.255.255"},
+		"IPv6":     {prefix: "fd00::/36", want: "ffff:ffff:f000::"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := Netmask(MustParsePrefix(tc.prefix)).String(); got != tc.want {
+				t.Errorf("Netmask(%s): want %s, got %s", tc.prefix, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSubnet(t *testing.T) {
+	type args struct {
+		prefix  netip.Prefix
+		newBits int
+		netNum  int64
+	}
+	type want struct {
+		subnet netip.Prefix
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"IPv4": {
+			reason: "should return the netNum'th subnet",
+			args:   args{prefix: MustParsePrefix("172.16.0.0/12"), newBits: 4, netNum: 2},
+			want:   want{subnet: MustParsePrefix("172.18.0.0/16")},
+		},
+		"IPv6": {
+			reason: "should return the netNum'th subnet of an IPv6 prefix",
+			args:   args{prefix: MustParsePrefix("fd00:fd12:3456:7890::/56"), newBits: 16, netNum: 162},
+			want:   want{subnet: MustParsePrefix("fd00:fd12:3456:7800:a200::/72")},
+		},
+		"IPv6Upper64Bits": {
+			reason: "should shift the netNum into the upper 64 bits of an IPv6 prefix",
+			args:   args{prefix: MustParsePrefix("2001:db8::/32"), newBits: 32, netNum: 0xbeef},
+			want:   want{subnet: MustParsePrefix("2001:db8:0:beef::/64")},
+		},
+		"PrefixOverflow": {
+			reason: "should fail when the subnet would be longer than the address",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/30"), newBits: 4, netNum: 0},
+			want:   want{err: &PrefixOverflowError{Prefix: MustParsePrefix("10.0.0.0/30"), NewBits: 4, Length: 34}},
+		},
+		"NetNumOutOfRange": {
+			reason: "should fail when the netNum cannot be represented in newBits",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/16"), newBits: 2, netNum: 4},
+			want:   want{err: &NetNumOutOfRangeError{Prefix: MustParsePrefix("10.0.0.0/16"), NewBits: 2, NetNum: 4}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			subnet, err := Subnet(tc.args.prefix, tc.args.newBits, tc.args.netNum)
+			if diff := cmp.Diff(tc.want.subnet, subnet, cmpNetip); diff != "" {
+				t.Errorf("%s\nSubnet(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, cmpNetip); diff != "" {
+				t.Errorf("%s\nSubnet(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSubnets(t *testing.T) {
+	type args struct {
+		prefix  netip.Prefix
+		newBits []int
+	}
+	type want struct {
+		subnets []netip.Prefix
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"IPv4": {
+			reason: "should allocate consecutive subnets, aligning each to its own size",
+			args:   args{prefix: MustParsePrefix("10.1.0.0/16"), newBits: []int{4, 4, 8, 4}},
+			want: want{subnets: []netip.Prefix{
+				MustParsePrefix("10.1.0.0/20"),
+				MustParsePrefix("10.1.16.0/20"),
+				MustParsePrefix("10.1.32.0/24"),
+				MustParsePrefix("10.1.48.0/20"),
+			}},
+		},
+		"IPv6": {
+			reason: "should allocate consecutive IPv6 subnets",
+			args:   args{prefix: MustParsePrefix("fd00:fd12:3456:7890::/56"), newBits: []int{16, 16, 16, 32}},
+			want: want{subnets: []netip.Prefix{
+				MustParsePrefix("fd00:fd12:3456:7800::/72"),
+				MustParsePrefix("fd00:fd12:3456:7800:100::/72"),
+				MustParsePrefix("fd00:fd12:3456:7800:200::/72"),
+				MustParsePrefix("fd00:fd12:3456:7800:300::/88"),
+			}},
+		},
+		"ZeroAddress": {
+			reason: "should allocate subnets of a prefix starting at the zero address",
+			args:   args{prefix: MustParsePrefix("0.0.0.0/0"), newBits: []int{1, 1}},
+			want: want{subnets: []netip.Prefix{
+				MustParsePrefix("0.0.0.0/1"),
+				MustParsePrefix("128.0.0.0/1"),
+			}},
+		},
+		"Exhausted": {
+			reason: "should fail when the prefix has no room left",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/24"), newBits: []int{1, 1, 1}},
+			want: want{err: &AddressSpaceExhaustedError{
+				Prefix: MustParsePrefix("10.0.0.0/24"),
+				Length: 25,
+				After:  MustParsePrefix("10.0.0.128/25"),
+			}},
+		},
+		"InvalidNewBits": {
+			reason: "should fail when a newBits element does not extend the prefix",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/24"), newBits: []int{1, 0}},
+			want: want{err: &InvalidNewBitsError{
+				Prefix:  MustParsePrefix("10.0.0.0/24"),
+				NewBits: 0,
+				Message: "must extend prefix by at least one bit",
+			}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			subnets, err := Subnets(tc.args.prefix, tc.args.newBits...)
+			if diff := cmp.Diff(tc.want.subnets, subnets, cmpNetip, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("%s\nSubnets(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, cmpNetip); diff != "" {
+				t.Errorf("%s\nSubnets(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSubnetsAround(t *testing.T) {
+	type args struct {
+		prefix   netip.Prefix
+		reserved []netip.Prefix
+		newBits  []int
+	}
+	type want struct {
+		subnets []netip.Prefix
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoneReserved": {
+			reason: "should allocate like Subnets if nothing is reserved",
+			args:   args{prefix: MustParsePrefix("10.1.0.0/16"), newBits: []int{4, 8}},
+			want: want{subnets: []netip.Prefix{
+				MustParsePrefix("10.1.0.0/20"),
+				MustParsePrefix("10.1.16.0/24"),
+			}},
+		},
+		"SkipLarger": {
+			reason: "should move a subnet past a larger reserved prefix",
+			args: args{
+				prefix:   MustParsePrefix("10.1.0.0/16"),
+				reserved: []netip.Prefix{MustParsePrefix("10.1.0.0/19")},
+				newBits:  []int{8, 8},
+			},
+			want: want{subnets: []netip.Prefix{
+				MustParsePrefix("10.1.32.0/24"),
+				MustParsePrefix("10.1.33.0/24"),
+			}},
+		},
+		"SkipSmaller": {
+			reason: "should move a subnet past a smaller reserved prefix it contains",
+			args: args{
+				prefix:   MustParsePrefix("10.1.0.0/16"),
+				reserved: []netip.Prefix{MustParsePrefix("10.1.17.0/24")},
+				newBits:  []int{4, 4},
+			},
+			want: want{subnets: []netip.Prefix{
+				MustParsePrefix("10.1.0.0/20"),
+				MustParsePrefix("10.1.32.0/20"),
+			}},
+		},
+		"Exhausted": {
+			reason: "should fail if the reserved prefixes leave no room",
+			args: args{
+				prefix:   MustParsePrefix("10.0.0.0/24"),
+				reserved: []netip.Prefix{MustParsePrefix("10.0.0.128/25")},
+				newBits:  []int{1, 1},
+			},
+			want: want{err: &AddressSpaceExhaustedError{
+				Prefix: MustParsePrefix("10.0.0.0/24"),
+				Length: 25,
+				After:  MustParsePrefix("10.0.0.0/25"),
+			}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			subnets, err := SubnetsAround(tc.args.prefix, tc.args.reserved, tc.args.newBits...)
+			if diff := cmp.Diff(tc.want.subnets, subnets, cmpNetip, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("%s\nSubnetsAround(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, cmpNetip); diff != "" {
+				t.Errorf("%s\nSubnetsAround(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestExclude(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		base     netip.Prefix
+		excluded []netip.Prefix
+		want     []netip.Prefix
+	}{
+		"Disjoint": {
+			reason:   "should return the base prefix when nothing overlaps",
+			base:     MustParsePrefix("10.0.0.0/24"),
+			excluded: []netip.Prefix{MustParsePrefix("10.0.1.0/24")},
+			want:     []netip.Prefix{MustParsePrefix("10.0.0.0/24")},
+		},
+		"Contained": {
+			reason:   "should split the base prefix around the excluded prefix",
+			base:     MustParsePrefix("10.0.0.0/24"),
+			excluded: []netip.Prefix{MustParsePrefix("10.0.0.64/26")},
+			want: []netip.Prefix{
+				MustParsePrefix("10.0.0.0/26"),
+				MustParsePrefix("10.0.0.128/25"),
+			},
+		},
+		"Covered": {
+			reason:   "should return nothing when the base prefix is excluded entirely",
+			base:     MustParsePrefix("10.0.0.0/24"),
+			excluded: []netip.Prefix{MustParsePrefix("10.0.0.0/16")},
+			want:     nil,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := Exclude(tc.base, tc.excluded...)
+			if diff := cmp.Diff(tc.want, got, cmpNetip, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("%s\nExclude(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUtilization(t *testing.T) {
+	cases := map[string]struct {
+		reason    string
+		pool      netip.Prefix
+		allocated []netip.Prefix
+		want      float64
+	}{
+		"Empty": {
+			reason: "should return zero when nothing is allocated",
+			pool:   MustParsePrefix("10.0.0.0/16"),
+			want:   0,
+		},
+		"Partial": {
+			reason: "should sum the fractions of the pool covered by each allocated prefix",
+			pool:   MustParsePrefix("10.0.0.0/16"),
+			allocated: []netip.Prefix{
+				MustParsePrefix("10.0.0.0/17"),
+				MustParsePrefix("10.0.128.0/18"),
+			},
+			want: 0.75,
+		},
+		"Outside": {
+			reason: "should ignore prefixes that are not contained in the pool",
+			pool:   MustParsePrefix("10.0.0.0/24"),
+			allocated: []netip.Prefix{
+				MustParsePrefix("10.0.0.0/25"),
+				MustParsePrefix("10.0.1.0/25"),
+			},
+			want: 0.5,
+		},
+		"Overlapping": {
+			reason: "should count addresses covered by overlapping prefixes once",
+			pool:   MustParsePrefix("10.0.0.0/16"),
+			allocated: []netip.Prefix{
+				MustParsePrefix("10.0.0.0/24"),
+				MustParsePrefix("10.0.0.0/17"),
+				MustParsePrefix("10.0.64.0/18"),
+				MustParsePrefix("10.0.128.0/18"),
+			},
+			want: 0.75,
+		},
+		"Covering": {
+			reason: "should return one when a prefix covers the whole pool",
+			pool:   MustParsePrefix("10.0.0.0/16"),
+			allocated: []netip.Prefix{
+				MustParsePrefix("10.0.0.0/17"),
+				MustParsePrefix("10.0.0.0/8"),
+			},
+			want: 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := Utilization(tc.pool, tc.allocated...); got != tc.want {
+				t.Errorf("%s\nUtilization(...): want %v, got %v", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestHashSubnet(t *testing.T) {
+	type args struct {
+		prefix    netip.Prefix
+		newBits   int
+		key       string
+		maxProbes int64
+		used      []netip.Prefix
+	}
+	type want struct {
+		subnet netip.Prefix
+		probes int64
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Free": {
+			reason: "should return the subnet the key hashes to if it is free",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/8"), newBits: 16, key: "uid-1"},
+			want:   want{subnet: MustParsePrefix("10.74.73.0/24"), probes: 1},
+		},
+		"Collision": {
+			reason: "should probe the next netNum if the subnet the key hashes to is used",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/8"), newBits: 16, key: "uid-1", used: []netip.Prefix{MustParsePrefix("10.74.73.128/25")}},
+			want:   want{subnet: MustParsePrefix("10.74.74.0/24"), probes: 2},
+		},
+		"WrapAround": {
+			reason: "should continue probing at the first netNum after the last one",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/24"), newBits: 2, key: "a", used: []netip.Prefix{MustParsePrefix("10.0.0.192/26")}},
+			want:   want{subnet: MustParsePrefix("10.0.0.0/26"), probes: 2},
+		},
+		"Exhausted": {
+			reason: "should fail if every subnet is used",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/24"), newBits: 1, key: "a", used: []netip.Prefix{MustParsePrefix("10.0.0.0/24")}},
+			want:   want{probes: 2, err: &NoFreeSubnetError{Prefix: MustParsePrefix("10.0.0.0/24"), Length: 25, Probes: 2}},
+		},
+		"MaxProbes": {
+			reason: "should stop probing after maxProbes subnets",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/8"), newBits: 16, key: "uid-1", maxProbes: 1, used: []netip.Prefix{MustParsePrefix("10.74.73.0/24")}},
+			want:   want{probes: 1, err: &NoFreeSubnetError{Prefix: MustParsePrefix("10.0.0.0/8"), Length: 24, Probes: 1}},
+		},
+		"DefaultMaxProbes": {
+			reason: "should stop probing after DefaultMaxProbes subnets if maxProbes is not set",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/8"), newBits: 16, key: "uid-1", used: []netip.Prefix{MustParsePrefix("10.0.0.0/8")}},
+			want:   want{probes: DefaultMaxProbes, err: &NoFreeSubnetError{Prefix: MustParsePrefix("10.0.0.0/8"), Length: 24, Probes: DefaultMaxProbes}},
+		},
+		"Overflow": {
+			reason: "should fail if the subnet would be longer than the address",
+			args:   args{prefix: MustParsePrefix("10.0.0.0/24"), newBits: 9, key: "a"},
+			want:   want{err: &PrefixOverflowError{Prefix: MustParsePrefix("10.0.0.0/24"), NewBits: 9, Length: 33}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			subnet, probes, err := HashSubnet(tc.args.prefix, tc.args.newBits, tc.args.key, tc.args.maxProbes, tc.args.used...)
+			if diff := cmp.Diff(tc.want.subnet, subnet, cmpNetip); diff != "" {
+				t.Errorf("%s\nHashSubnet(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.probes, probes); diff != "" {
+				t.Errorf("%s\nHashSubnet(...): -want probes, +got probes:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, cmpNetip); diff != "" {
+				t.Errorf("%s\nHashSubnet(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestULAPrefix(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		seed   string
+		want   netip.Prefix
+	}{
+		"UID": {
+			reason: "should derive the Global ID from the SHA-1 digest of the seed",
+			seed:   "uid-1",
+			want:   MustParsePrefix("fd31:d269:53cb::/48"),
+		},
+		"Literal": {
+			reason: "should derive a different Global ID from a different seed",
+			seed:   "prod",
+			want:   MustParsePrefix("fd54:a27:5ac9::/48"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ULAPrefix(tc.seed)
+			if diff := cmp.Diff(tc.want, got, cmpNetip); diff != "" {
+				t.Errorf("%s\nULAPrefix(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestEUI64(t *testing.T) {
+	type args struct {
+		prefix netip.Prefix
+		mac    string
+	}
+	type want struct {
+		addr netip.Addr
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"MAC48": {
+			reason: "should insert ff:fe and invert the universal/local bit of a 48 bit MAC",
+			args:   args{prefix: MustParsePrefix("2001:db8:1:2::/64"), mac: "00:1a:2b:3c:4d:5e"},
+			want:   want{addr: netip.MustParseAddr("2001:db8:1:2:21a:2bff:fe3c:4d5e")},
+		},
+		"MAC64": {
+			reason: "should invert the universal/local bit of a 64 bit MAC",
+			args:   args{prefix: MustParsePrefix("2001:db8:1:2::/64"), mac: "02:1a:2b:3c:4d:5e:6f:70"},
+			want:   want{addr: netip.MustParseAddr("2001:db8:1:2:1a:2b3c:4d5e:6f70")},
+		},
+		"NotSlash64": {
+			reason: "should fail for prefixes that are not /64",
+			args:   args{prefix: MustParsePrefix("2001:db8::/48"), mac: "00:1a:2b:3c:4d:5e"},
+			want:   want{err: &InvalidPrefixError{Prefix: "2001:db8::/48"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mac, _ := net.ParseMAC(tc.args.mac)
+			addr, err := EUI64(tc.args.prefix, mac)
+			if diff := cmp.Diff(tc.want.addr, addr, cmp.Comparer(func(a, b netip.Addr) bool { return a == b })); diff != "" {
+				t.Errorf("%s\nEUI64(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.IgnoreFields(InvalidPrefixError{}, "Err")); diff != "" {
+				t.Errorf("%s\nEUI64(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNAT64(t *testing.T) {
+	v4 := netip.MustParseAddr("192.0.2.33")
+
+	// The examples of RFC 6052 section 2.4.
+	cases := map[string]struct {
+		prefix netip.Prefix
+		want   netip.Addr
+	}{
+		"32":        {prefix: MustParsePrefix("2001:db8::/32"), want: netip.MustParseAddr("2001:db8:c000:221::")},
+		"40":        {prefix: MustParsePrefix("2001:db8:100::/40"), want: netip.MustParseAddr("2001:db8:1c0:2:21::")},
+		"48":        {prefix: MustParsePrefix("2001:db8:122::/48"), want: netip.MustParseAddr("2001:db8:122:c000:2:2100::")},
+		"56":        {prefix: MustParsePrefix("2001:db8:122:300::/56"), want: netip.MustParseAddr("2001:db8:122:3c0:0:221::")},
+		"64":        {prefix: MustParsePrefix("2001:db8:122:344::/64"), want: netip.MustParseAddr("2001:db8:122:344:c0:2:2100:0")},
+		"96":        {prefix: MustParsePrefix("2001:db8:122:344::/96"), want: netip.MustParseAddr("2001:db8:122:344::192.0.2.33")},
+		"WellKnown": {prefix: MustParsePrefix("64:ff9b::/96"), want: netip.MustParseAddr("64:ff9b::c000:221")},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			addr, err := NAT64(tc.prefix, v4)
+			if err != nil {
+				t.Fatalf("NAT64(...): %v", err)
+			}
+			if addr != tc.want {
+				t.Errorf("NAT64(%s, %s): want %s, got %s", tc.prefix, v4, tc.want, addr)
+			}
+		})
+	}
+
+	t.Run("InvalidLength", func(t *testing.T) {
+		_, err := NAT64(MustParsePrefix("2001:db8::/44"), v4)
+		want := &InvalidPrefixError{Prefix: "2001:db8::/44"}
+		if diff := cmp.Diff(want, err, cmpopts.IgnoreFields(InvalidPrefixError{}, "Err")); diff != "" {
+			t.Errorf("NAT64(...): -want err, +got err:\n%s", diff)
+		}
+	})
+}
+
+func TestIPv4Mapped(t *testing.T) {
+	addr, err := IPv4Mapped(netip.MustParseAddr("10.0.0.1"))
+	if err != nil {
+		t.Fatalf("IPv4Mapped(...): %v", err)
+	}
+	if want := "::ffff:10.0.0.1"; addr.String() != want {
+		t.Errorf("IPv4Mapped(...): want %s, got %s", want, addr)
+	}
+
+	_, err = IPv4Mapped(netip.MustParseAddr("fd00::1"))
+	want := &InvalidAddressError{Address: "fd00::1", Message: "must be an IPv4 address"}
+	if diff := cmp.Diff(want, err); diff != "" {
+		t.Errorf("IPv4Mapped(...): -want err, +got err:\n%s", diff)
+	}
+}
+
+func TestReverseZones(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		prefix netip.Prefix
+		want   []string
+	}{
+		"IPv4Octet": {
+			reason: "should return a single zone for a prefix on an octet boundary",
+			prefix: MustParsePrefix("10.1.0.0/16"),
+			want:   []string{"1.10.in-addr.arpa"},
+		},
+		"IPv4Split": {
+			reason: "should split a prefix into the zones of the next octet boundary",
+			prefix: MustParsePrefix("10.0.0.0/23"),
+			want:   []string{"0.0.10.in-addr.arpa", "1.0.10.in-addr.arpa"},
+		},
+		"IPv4Classless": {
+			reason: "should return the RFC 2317 name of a prefix longer than /24",
+			prefix: MustParsePrefix("192.0.2.64/26"),
+			want:   []string{"64/26.2.0.192.in-addr.arpa"},
+		},
+		"IPv4Host": {
+			reason: "should return the name of a single address",
+			prefix: MustParsePrefix("192.0.2.1/32"),
+			want:   []string{"1.2.0.192.in-addr.arpa"},
+		},
+		"IPv6Nibble": {
+			reason: "should return a single zone for a prefix on a nibble boundary",
+			prefix: MustParsePrefix("2001:db8::/32"),
+			want:   []string{"8.b.d.0.1.0.0.2.ip6.arpa"},
+		},
+		"IPv6Split": {
+			reason: "should split a prefix into the zones of the next nibble boundary",
+			prefix: MustParsePrefix("2001:db8:0:4::/62"),
+			want: []string{
+				"4.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+				"5.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+				"6.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+				"7.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ReverseZones(tc.prefix)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nReverseZones(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package cidr
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Reasons describing why a calculation failed. They are suitable for use as
+// the reason of a Crossplane result or condition.
+const (
+	ReasonInvalidPrefix     = "InvalidPrefix"
+	ReasonInvalidNewBits    = "InvalidNewBits"
+	ReasonPrefixOverflow    = "PrefixOverflow"
+	ReasonNetNumOutOfRange  = "NetNumOutOfRange"
+	ReasonHostNumOutOfRange = "HostNumOutOfRange"
+	ReasonPoolExhausted     = "PoolExhausted"
+	ReasonInvalidAddress    = "InvalidAddress"
+)
+
+// InvalidPrefixError is returned when a prefix cannot be parsed as CIDR.
+type InvalidPrefixError struct {
+	Prefix string
+	Err    error
+}
+
+func (e *InvalidPrefixError) Error() string {
+	return fmt.Sprintf("invalid CIDR prefix %q: %v", e.Prefix, e.Err)
+}
+
+// Unwrap returns the underlying parse error.
+func (e *InvalidPrefixError) Unwrap() error { return e.Err }
+
+// Reason returns the reason of the error.
+func (e *InvalidPrefixError) Reason() string { return ReasonInvalidPrefix }
+
+// InvalidNewBitsError is returned when newBits cannot be used to extend a
+// prefix, e.g. because it is smaller than one.
+type InvalidNewBitsError struct {
+	Prefix  netip.Prefix
+	NewBits int
+	Message string
+}
+
+func (e *InvalidNewBitsError) Error() string {
+	return fmt.Sprintf("invalid newBits %d for prefix %s: %s", e.NewBits, e.Prefix, e.Message)
+}
+
+// Reason returns the reason of the error.
+func (e *InvalidNewBitsError) Reason() string { return ReasonInvalidNewBits }
+
+// PrefixOverflowError is returned when extending a prefix by newBits would
+// exceed the length of its address family.
+type PrefixOverflowError struct {
+	Prefix  netip.Prefix
+	NewBits int
+	Length  int
+}
+
+func (e *PrefixOverflowError) Error() string {
+	return fmt.Sprintf("extending prefix %s by %d bits would extend it to %d bits, which is too long for an %s address", e.Prefix, e.NewBits, e.Length, Protocol(e.Prefix))
+}
+
+// Reason returns the reason of the error.
+func (e *PrefixOverflowError) Reason() string { return ReasonPrefixOverflow }
+
+// NetNumOutOfRangeError is returned when a netNum cannot be represented with
+// newBits binary digits.
+type NetNumOutOfRangeError struct {
+	Prefix  netip.Prefix
+	NewBits int
+	NetNum  int64
+}
+
+func (e *NetNumOutOfRangeError) Error() string {
+	return fmt.Sprintf("netnum %d is out of range for prefix %s extended by %d bits", e.NetNum, e.Prefix, e.NewBits)
+}
+
+// Reason returns the reason of the error.
+func (e *NetNumOutOfRangeError) Reason() string { return ReasonNetNumOutOfRange }
+
+// HostNumOutOfRangeError is returned when a hostNum does not fit into the
+// host part of a prefix.
+type HostNumOutOfRangeError struct {
+	Prefix  netip.Prefix
+	HostNum int64
+}
+
+func (e *HostNumOutOfRangeError) Error() string {
+	return fmt.Sprintf("hostnum %d is out of range for prefix %s", e.HostNum, e.Prefix)
+}
+
+// Reason returns the reason of the error.
+func (e *HostNumOutOfRangeError) Reason() string { return ReasonHostNumOutOfRange }
+
+// AddressSpaceExhaustedError is returned when a prefix has no room left for
+// another subnet of the requested length.
+type AddressSpaceExhaustedError struct {
+	Prefix netip.Prefix
+	Length int
+	After  netip.Prefix
+}
+
+func (e *AddressSpaceExhaustedError) Error() string {
+	return fmt.Sprintf("not enough remaining address space in %s for a subnet with a prefix of %d bits after %s", e.Prefix, e.Length, e.After)
+}
+
+// Reason returns the reason of the error.
+func (e *AddressSpaceExhaustedError) Reason() string { return ReasonPoolExhausted }
+
+// NoFreeSubnetError is returned when every probed subnet of a prefix overlaps
+// a used prefix.
+type NoFreeSubnetError struct {
+	Prefix netip.Prefix
+	Length int
+	Probes int64
+}
+
+func (e *NoFreeSubnetError) Error() string {
+	return fmt.Sprintf("no free subnet with a prefix of %d bits in %s after %d probes", e.Length, e.Prefix, e.Probes)
+}
+
+// Reason returns the reason of the error.
+func (e *NoFreeSubnetError) Reason() string { return ReasonPoolExhausted }
+
+// InvalidAddressError is returned when an address cannot be used for a
+// calculation, e.g. because it is of the wrong family.
+type InvalidAddressError struct {
+	Address string
+	Message string
+}
+
+func (e *InvalidAddressError) Error() string {
+	return fmt.Sprintf("invalid address %q: %s", e.Address, e.Message)
+}
+
+// Reason returns the reason of the error.
+func (e *InvalidAddressError) Reason() string { return ReasonInvalidAddress }
@@ -0,0 +1,74 @@
+package cidr
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"net/netip"
+)
+
+// MaxHashNewBits is the largest newBits HashSubnet can hash a key into.
+const MaxHashNewBits = 63
+
+// DefaultMaxProbes is the number of subnets HashSubnet probes at most unless
+// a maxProbes is supplied. It bounds the time it takes to find a free subnet
+// in a large netNum space of which most subnets are used.
+const DefaultMaxProbes = 4096
+
+// HashNetNum returns the netNum the supplied key hashes to in the netNum space
+// of newBits, i.e. a number between 0 and 2^newBits-1. It uses the leading
+// bits of the SHA-256 digest of the key, so the result is stable across
+// releases and platforms.
+func HashNetNum(key string, newBits int) int64 {
+	if newBits <= 0 {
+		return 0
+	}
+	sum := sha256.Sum256([]byte(key))
+	return int64(binary.BigEndian.Uint64(sum[:8]) >> (64 - newBits))
+}
+
+// HashSubnet returns the subnet of the supplied prefix, extended by newBits,
+// whose netNum the supplied key hashes to. If that subnet overlaps any of the
+// used prefixes the following netNums are probed in order, wrapping around at
+// the end of the netNum space, until a free subnet is found or maxProbes
+// subnets were tried. A maxProbes of zero or less probes DefaultMaxProbes
+// subnets. No more subnets than the netNum space holds are probed.
+//
+// It returns the subnet and the number of probes it took to find it.
+func HashSubnet(prefix netip.Prefix, newBits int, key string, maxProbes int64, used ...netip.Prefix) (netip.Prefix, int64, error) {
+	p := prefix.Masked()
+	if newBits > MaxHashNewBits {
+		return netip.Prefix{}, 0, &InvalidNewBitsError{Prefix: p, NewBits: newBits, Message: "must not exceed 63 to hash a key"}
+	}
+	if _, err := Subnet(p, newBits, 0); err != nil {
+		return netip.Prefix{}, 0, err
+	}
+
+	// mask is the largest netNum, which is also the number of netNums less
+	// one.
+	mask := int64(1)<<newBits - 1
+	if maxProbes <= 0 {
+		maxProbes = DefaultMaxProbes
+	}
+	if maxProbes-1 > mask {
+		maxProbes = mask + 1
+	}
+
+	start := HashNetNum(key, newBits)
+	for probe := int64(0); probe < maxProbes; probe++ {
+		subnet, _ := Subnet(p, newBits, (start+probe)&mask)
+		if !overlapsAny(subnet, used) {
+			return subnet, probe + 1, nil
+		}
+	}
+	return netip.Prefix{}, maxProbes, &NoFreeSubnetError{Prefix: p, Length: p.Bits() + newBits, Probes: maxProbes}
+}
+
+// overlapsAny returns true if p overlaps any of the supplied prefixes.
+func overlapsAny(p netip.Prefix, prefixes []netip.Prefix) bool {
+	for _, o := range prefixes {
+		if p.Overlaps(o) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package cidr
+
+import (
+	"net/netip"
+)
+
+// Host returns the address of the hostNum'th host in the supplied prefix. A
+// negative hostNum counts backwards from the end of the prefix, so -1 is the
+// last address.
+// https://developer.hashicorp.com/terraform/language/functions/cidrhost
+func Host(prefix netip.Prefix, hostNum int64) (netip.Addr, error) {
+	p := prefix.Masked()
+	hostLen := p.Addr().BitLen() - p.Bits()
+
+	if hostNum >= 0 {
+		if hostLen < 63 && hostNum >= int64(1)<<uint(hostLen) {
+			return netip.Addr{}, &HostNumOutOfRangeError{Prefix: p, HostNum: hostNum}
+		}
+		return toAddr(fromAddr(p.Addr()).add(uint64(hostNum)), p.Addr()), nil
+	}
+
+	// Negate without overflowing for math.MinInt64.
+	n := uint64(^hostNum) + 1
+	if hostLen < 64 && n > uint64(1)<<uint(hostLen) {
+		return netip.Addr{}, &HostNumOutOfRangeError{Prefix: p, HostNum: hostNum}
+	}
+	return toAddr(fromAddr(LastAddr(p)).sub(n-1), p.Addr()), nil
+}
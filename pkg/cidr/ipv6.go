@@ -0,0 +1,79 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+)
+
+// EUI64Length is the length of the prefixes EUI64 builds addresses in.
+const EUI64Length = 64
+
+// NAT64PrefixLengths are the prefix lengths RFC 6052 section 2.2 allows IPv4
+// addresses to be embedded in.
+var NAT64PrefixLengths = []int{32, 40, 48, 56, 64, 96}
+
+// EUI64 returns the address of the supplied /64 IPv6 prefix whose interface
+// identifier is the modified EUI-64 of the supplied MAC address, as described
+// in RFC 4291 appendix A. A 48 bit MAC is extended with ff:fe in its middle;
+// the universal/local bit is inverted for both 48 and 64 bit MACs.
+func EUI64(prefix netip.Prefix, mac net.HardwareAddr) (netip.Addr, error) {
+	p := prefix.Masked()
+	if !p.Addr().Is6() || p.Addr().Is4In6() || p.Bits() != EUI64Length {
+		return netip.Addr{}, &InvalidPrefixError{Prefix: p.String(), Err: fmt.Errorf("must be an IPv6 prefix of length %d", EUI64Length)}
+	}
+
+	var iid []byte
+	switch len(mac) {
+	case 6:
+		iid = []byte{mac[0], mac[1], mac[2], 0xff, 0xfe, mac[3], mac[4], mac[5]}
+	case 8:
+		iid = slices.Clone(mac)
+	default:
+		return netip.Addr{}, &InvalidAddressError{Address: mac.String(), Message: "must be a 48 or 64 bit MAC address"}
+	}
+	iid[0] ^= 0x02
+
+	a := p.Addr().As16()
+	copy(a[8:], iid)
+	return netip.AddrFrom16(a), nil
+}
+
+// NAT64 returns the IPv4-embedded IPv6 address of the supplied IPv4 address in
+// the supplied NAT64 prefix, as described in RFC 6052 section 2.2. The prefix
+// must have one of the NAT64PrefixLengths. Bits 64 to 71 of the address are
+// left zero, so the IPv4 address is split around them for prefixes of lengths
+// 40 to 56.
+func NAT64(prefix netip.Prefix, v4 netip.Addr) (netip.Addr, error) {
+	p := prefix.Masked()
+	if !p.Addr().Is6() || p.Addr().Is4In6() || !slices.Contains(NAT64PrefixLengths, p.Bits()) {
+		return netip.Addr{}, &InvalidPrefixError{Prefix: p.String(), Err: fmt.Errorf("must be an IPv6 prefix of length %v", NAT64PrefixLengths)}
+	}
+	if !v4.Is4() {
+		return netip.Addr{}, &InvalidAddressError{Address: v4.String(), Message: "must be an IPv4 address"}
+	}
+
+	a := p.Addr().As16()
+	b := v4.As4()
+	// The IPv4 address starts right after the prefix and skips byte 8, the
+	// u octet.
+	i := p.Bits() / 8
+	for _, octet := range b {
+		if i == 8 {
+			i++
+		}
+		a[i] = octet
+		i++
+	}
+	return netip.AddrFrom16(a), nil
+}
+
+// IPv4Mapped returns the IPv4-mapped IPv6 address of the supplied IPv4
+// address, i.e. ::ffff:a.b.c.d as described in RFC 4291 section 2.5.5.2.
+func IPv4Mapped(v4 netip.Addr) (netip.Addr, error) {
+	if !v4.Is4() {
+		return netip.Addr{}, &InvalidAddressError{Address: v4.String(), Message: "must be an IPv4 address"}
+	}
+	return netip.AddrFrom16(v4.As16()), nil
+}
@@ -0,0 +1,67 @@
+package cidr
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Suffixes of reverse DNS zones.
+const (
+	ReverseZoneIPv4 = "in-addr.arpa"
+	ReverseZoneIPv6 = "ip6.arpa"
+)
+
+// ReverseZones returns the names of the reverse DNS zones that cover exactly
+// the addresses of the supplied prefix. Reverse zones are delegated on octet
+// boundaries for IPv4 and on nibble boundaries for IPv6, so a prefix that is
+// not on such a boundary is split into the zones of its subnets on the next
+// one, e.g. 10.0.0.0/15 into 0.10.in-addr.arpa and 1.10.in-addr.arpa.
+//
+// IPv4 prefixes longer than /24 cannot be split into whole zones. Their name
+// is the RFC 2317 classless delegation name instead, e.g.
+// 64/26.2.0.192.in-addr.arpa for 192.0.2.64/26.
+func ReverseZones(prefix netip.Prefix) []string {
+	p := prefix.Masked()
+	label := 8
+	if p.Addr().Is6() {
+		label = 4
+	}
+
+	if p.Addr().Is4() && p.Bits() > 24 && p.Bits() < Bits32 {
+		b := p.Addr().As4()
+		return []string{fmt.Sprintf("%d/%d.%s", b[3], p.Bits(), reverseName(netip.PrefixFrom(p.Addr(), 24)))}
+	}
+
+	newBits := (label - p.Bits()%label) % label
+	zones := make([]string, 0, 1<<newBits)
+	for netNum := int64(0); netNum < int64(1)<<newBits; netNum++ {
+		s, _ := Subnet(p, newBits, netNum)
+		zones = append(zones, reverseName(s))
+	}
+	return zones
+}
+
+// reverseName returns the reverse DNS name of a prefix on an octet boundary
+// for IPv4 or a nibble boundary for IPv6.
+func reverseName(p netip.Prefix) string {
+	var labels []string
+	if p.Addr().Is4() {
+		b := p.Addr().As4()
+		for i := p.Bits()/8 - 1; i >= 0; i-- {
+			labels = append(labels, strconv.Itoa(int(b[i])))
+		}
+		return strings.Join(append(labels, ReverseZoneIPv4), ".")
+	}
+
+	b := p.Addr().As16()
+	for i := p.Bits()/4 - 1; i >= 0; i-- {
+		nibble := b[i/2] >> 4
+		if i%2 == 1 {
+			nibble = b[i/2] & 0x0f
+		}
+		labels = append(labels, strconv.FormatUint(uint64(nibble), 16))
+	}
+	return strings.Join(append(labels, ReverseZoneIPv6), ".")
+}
@@ -0,0 +1,95 @@
+package cidr
+
+import (
+	"math"
+	"net/netip"
+	"sort"
+)
+
+// Contains returns true if outer contains every address of inner.
+func Contains(outer, inner netip.Prefix) bool {
+	return outer.Addr().BitLen() == inner.Addr().BitLen() && outer.Bits() <= inner.Bits() && outer.Contains(inner.Addr())
+}
+
+// Overlapping returns the first two of the supplied prefixes that share at
+// least one address, and false if no prefixes overlap.
+func Overlapping(prefixes ...netip.Prefix) (netip.Prefix, netip.Prefix, bool) {
+	for i := range prefixes {
+		for j := i + 1; j < len(prefixes); j++ {
+			if prefixes[i].Overlaps(prefixes[j]) {
+				return prefixes[i], prefixes[j], true
+			}
+		}
+	}
+	return netip.Prefix{}, netip.Prefix{}, false
+}
+
+// Exclude returns the smallest set of prefixes that covers every address of
+// base that is not covered by any of the excluded prefixes. The result is
+// sorted by address.
+func Exclude(base netip.Prefix, excluded ...netip.Prefix) []netip.Prefix {
+	remaining := []netip.Prefix{base.Masked()}
+	for _, e := range excluded {
+		e = e.Masked()
+		next := remaining[:0:0]
+		for _, r := range remaining {
+			next = append(next, exclude(r, e)...)
+		}
+		remaining = next
+	}
+	sort.Slice(remaining, func(i, j int) bool {
+		return remaining[i].Addr().Less(remaining[j].Addr())
+	})
+	return remaining
+}
+
+// exclude returns the prefixes that cover p without e.
+func exclude(p, e netip.Prefix) []netip.Prefix {
+	switch {
+	case !p.Overlaps(e):
+		return []netip.Prefix{p}
+	case Contains(e, p):
+		return nil
+	}
+	// p strictly contains e, so split it in halves and recurse.
+	lower, _ := Subnet(p, 1, 0)
+	upper, _ := Subnet(p, 1, 1)
+	return append(exclude(lower, e), exclude(upper, e)...)
+}
+
+// Utilization returns the fraction of the addresses of pool that are covered
+// by the supplied allocated prefixes. Allocated prefixes that are not
+// contained in pool are ignored, unless they contain the whole pool. Addresses
+// covered by more than one allocated prefix are only counted once.
+func Utilization(pool netip.Prefix, allocated ...netip.Prefix) float64 {
+	pool = pool.Masked()
+	covered := make([]netip.Prefix, 0, len(allocated))
+	for _, a := range allocated {
+		a = a.Masked()
+		switch {
+		case Contains(a, pool):
+			return 1
+		case Contains(pool, a):
+			covered = append(covered, a)
+		}
+	}
+
+	// Sorted by address and length a prefix precedes all prefixes it
+	// contains, so nested prefixes directly follow the prefix containing them.
+	sort.Slice(covered, func(i, j int) bool {
+		if c := covered[i].Addr().Compare(covered[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return covered[i].Bits() < covered[j].Bits()
+	})
+	var u float64
+	var last netip.Prefix
+	for _, c := range covered {
+		if last.IsValid() && Contains(last, c) {
+			continue
+		}
+		u += math.Ldexp(1, pool.Bits()-c.Bits())
+		last = c
+	}
+	return u
+}
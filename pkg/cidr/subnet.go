@@ -0,0 +1,27 @@
+package cidr
+
+import (
+	"math/bits"
+	"net/netip"
+)
+
+// Subnet returns the netNum'th subnet of the supplied prefix, extended by
+// newBits.
+// https://developer.hashicorp.com/terraform/language/functions/cidrsubnet
+func Subnet(prefix netip.Prefix, newBits int, netNum int64) (netip.Prefix, error) {
+	p := prefix.Masked()
+	if newBits < 0 {
+		return netip.Prefix{}, &InvalidNewBitsError{Prefix: p, NewBits: newBits, Message: "must not be negative"}
+	}
+	addrLen := p.Addr().BitLen()
+	length := p.Bits() + newBits
+	if length > addrLen {
+		return netip.Prefix{}, &PrefixOverflowError{Prefix: p, NewBits: newBits, Length: length}
+	}
+	if netNum < 0 || bits.Len64(uint64(netNum)) > newBits {
+		return netip.Prefix{}, &NetNumOutOfRangeError{Prefix: p, NewBits: newBits, NetNum: netNum}
+	}
+
+	u := fromAddr(p.Addr()).or(uint128{lo: uint64(netNum)}.shl(addrLen - length))
+	return netip.PrefixFrom(toAddr(u, p.Addr()), length), nil
+}
@@ -0,0 +1,111 @@
+package cidr
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// Subnets returns a sequence of consecutive subnets of the supplied prefix,
+// one for each newBits element, each extending the prefix by that many bits.
+// https://developer.hashicorp.com/terraform/language/functions/cidrsubnets
+func Subnets(prefix netip.Prefix, newBits ...int) ([]netip.Prefix, error) {
+	return AppendSubnets(nil, prefix, newBits...)
+}
+
+// AppendSubnets is like Subnets but appends the subnets to dst, which allows
+// callers computing many subnets to reuse a single slice.
+func AppendSubnets(dst []netip.Prefix, prefix netip.Prefix, newBits ...int) ([]netip.Prefix, error) {
+	return appendSubnets(dst, prefix, nil, newBits)
+}
+
+// SubnetsAround is like Subnets but routes around the reserved prefixes: a
+// subnet that would overlap any of them is moved to the first address after
+// the reserved prefix it overlaps, aligned to its length.
+func SubnetsAround(prefix netip.Prefix, reserved []netip.Prefix, newBits ...int) ([]netip.Prefix, error) {
+	return appendSubnets(nil, prefix, reserved, newBits)
+}
+
+func appendSubnets(dst []netip.Prefix, prefix netip.Prefix, reserved []netip.Prefix, newBits []int) ([]netip.Prefix, error) {
+	p := prefix.Masked()
+	if len(newBits) == 0 {
+		return dst, nil
+	}
+
+	addrLen := p.Addr().BitLen()
+	for _, nb := range newBits {
+		if nb < 1 {
+			return dst, &InvalidNewBitsError{Prefix: p, NewBits: nb, Message: "must extend prefix by at least one bit"}
+		}
+		// For portability with 32-bit systems where the subnet number
+		// will be a 32-bit int, we only allow extension of 32 bits in
+		// one call even if we're running on a 64-bit machine.
+		// (Of course, this is significant only for IPv6.)
+		if nb > Bits32 {
+			return dst, &InvalidNewBitsError{Prefix: p, NewBits: nb, Message: "may not extend prefix by more than 32 bits"}
+		}
+		if p.Bits()+nb > addrLen {
+			return dst, &PrefixOverflowError{Prefix: p, NewBits: nb, Length: p.Bits() + nb}
+		}
+	}
+
+	start := len(dst)
+	first := netip.PrefixFrom(p.Addr(), p.Bits()+newBits[0])
+	current, ok := skipReserved(p, first, reserved)
+	if !ok {
+		return dst[:start], &AddressSpaceExhaustedError{Prefix: p, Length: first.Bits(), After: first}
+	}
+	dst = append(dst, current)
+	for _, nb := range newBits[1:] {
+		length := p.Bits() + nb
+		next, rollover := nextSubnet(current, length)
+		if !rollover {
+			next, ok = skipReserved(p, next, reserved)
+		}
+		if rollover || !ok || !p.Contains(next.Addr()) {
+			// If we run out of suffix bits in the base CIDR prefix then
+			// nextSubnet will start incrementing the prefix bits, which
+			// we don't allow because it would then allocate addresses
+			// outside of the caller's given prefix.
+			return dst[:start], &AddressSpaceExhaustedError{Prefix: p, Length: length, After: current}
+		}
+		current = next
+		dst = append(dst, current)
+	}
+
+	return dst, nil
+}
+
+// skipReserved returns the first subnet of the length of candidate, starting
+// at candidate, that does not overlap any of the reserved prefixes. It returns
+// false if no such subnet is left in p.
+func skipReserved(p, candidate netip.Prefix, reserved []netip.Prefix) (netip.Prefix, bool) {
+	for {
+		if !p.Contains(candidate.Addr()) {
+			return candidate, false
+		}
+		i := slices.IndexFunc(reserved, candidate.Overlaps)
+		if i < 0 {
+			return candidate, true
+		}
+		// The subnet after the reserved prefix, aligned to the length of
+		// the candidate, is also the subnet after the candidate if the
+		// reserved prefix is the smaller of the two.
+		next, rollover := nextSubnet(reserved[i], candidate.Bits())
+		if rollover {
+			return candidate, false
+		}
+		candidate = next
+	}
+}
+
+// nextSubnet returns the subnet of the supplied length immediately following
+// the supplied prefix. It returns true if the end of the address space was
+// reached.
+func nextSubnet(p netip.Prefix, length int) (netip.Prefix, bool) {
+	last := LastAddr(netip.PrefixFrom(LastAddr(p), length))
+	next := last.Next()
+	if !next.IsValid() {
+		return netip.PrefixFrom(toAddr(uint128{}, p.Addr()), length), true
+	}
+	return netip.PrefixFrom(next, length), false
+}
@@ -0,0 +1,24 @@
+package cidr
+
+import (
+	"crypto/sha1"
+	"net/netip"
+)
+
+// ULALength is the length of a Unique Local IPv6 Unicast Address prefix.
+const ULALength = 48
+
+// ULAPrefix returns the locally assigned Unique Local IPv6 Unicast Address
+// prefix of length 48 derived from the supplied seed. It follows the algorithm
+// of RFC 4193 section 3.2.2, but hashes the seed instead of the time of day and
+// an EUI-64 identifier, so the same seed always yields the same prefix:
+// the Global ID is the least significant 40 bits of the SHA-1 digest of the
+// seed, and is prepended with fd, i.e. the fc00::/7 prefix with the L bit set.
+func ULAPrefix(seed string) netip.Prefix {
+	sum := sha1.Sum([]byte(seed))
+
+	var a [16]byte
+	a[0] = 0xfd
+	copy(a[1:6], sum[len(sum)-5:])
+	return netip.PrefixFrom(netip.AddrFrom16(a), ULALength)
+}
@@ -0,0 +1,97 @@
+package operation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/tidwall/gjson"
+
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/request"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// ExtractKeys extracts keys from a dotted list of keys while considering quoted strings a single value.
+func ExtractKeys(input string) []string {
+	var keys []string
+	var keyBuilder strings.Builder
+	inQuotes := false
+
+	for i := 0; i < len(input); i++ {
+		char := input[i]
+
+		if char == '\'' {
+			inQuotes = !inQuotes
+		} else if char == '.' && !inQuotes {
+			keys = append(keys, keyBuilder.String())
+			keyBuilder.Reset()
+		} else {
+			keyBuilder.WriteByte(char)
+		}
+	}
+
+	if keyBuilder.Len() > 0 {
+		keys = append(keys, keyBuilder.String())
+	}
+
+	return keys
+}
+
+// GetPrefixField returns the prefix value from the defined field
+func GetPrefixField(prefixField string, oxr *resource.Composite, req *fnv1.RunFunctionRequest) (string, error) {
+	prefix := ""
+	if strings.HasPrefix(prefixField, "desired.") {
+		if strings.HasPrefix(prefixField, "desired.composite.") {
+			dxr, err := request.GetDesiredCompositeResource(req)
+			if err != nil {
+				return "", errors.Wrapf(err, "cannot get desired composite resource from %s for %s", prefixField, dxr.Resource.GetKind())
+			}
+			dxrPrefix, err := dxr.Resource.GetString(strings.Replace(prefixField, "desired.composite.resource.", "", 1))
+			prefix = dxrPrefix
+			if err != nil {
+				return "", errors.Wrapf(err, "cannot get prefix from field %s for %s", prefixField, dxr.Resource.GetKind())
+			}
+		} else if strings.HasPrefix(prefixField, "desired.resources.") {
+			properties := ExtractKeys(strings.Replace(prefixField, "desired.resources.", "", 1))
+			resourceName := resource.Name(properties[0])
+			dxr, err := request.GetDesiredComposedResources(req)
+			if err != nil {
+				return "", errors.Wrapf(err, "cannot get desired composed resource from %s", prefixField)
+			}
+			if val, ok := dxr[resourceName]; ok {
+				dxrPrefix, err := val.Resource.GetString(strings.Replace(prefixField, "desired.resources."+properties[0]+".resource.", "", 1))
+				prefix = dxrPrefix
+				if err != nil {
+					return "", errors.Wrapf(err, "cannot get prefix for resource with name %s from field %s", resourceName, prefixField)
+				}
+			} else {
+				return "", errors.New(fmt.Sprintf("No composed resource with name %s found for field %s", resourceName, prefixField))
+			}
+		}
+	} else if strings.HasPrefix(prefixField, "context.") {
+		ctxField := strings.Replace(prefixField, "context.", "", 1)
+		ctx := req.Context
+		if ctx == nil {
+			return "", errors.New("No context available")
+		}
+		json, err := json.Marshal(ctx)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to marshall context to json for extraction of field %s", prefixField)
+		}
+		prefixValue := gjson.GetBytes(json, ctxField)
+		if !prefixValue.Exists() {
+			return "", errors.New(fmt.Sprintf("Failed to extract value for %s from json context %s", ctxField, json))
+		}
+		prefix = prefixValue.Str
+	} else {
+		prefixValue, err := oxr.Resource.GetString(prefixField)
+		prefix = prefixValue
+		if err != nil {
+			return "", errors.Wrapf(err, "cannot get prefix from field %s for %s", prefixField, oxr.Resource.GetKind())
+		}
+	}
+	return prefix, nil
+}
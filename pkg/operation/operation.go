@@ -0,0 +1,127 @@
+// Package operation defines the CidrOperation interface implemented by every
+// cidrFunc, the Registry cidrFuncs are called from by name, and the Resolver
+// that resolves their inputs.
+package operation
+
+import (
+	"net/netip"
+	"sort"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+)
+
+// A CidrOperation implements a single cidrFunc. A new CidrOperation is created
+// for every function invocation, so implementations may keep the resolved
+// inputs and computed results in their own fields.
+type CidrOperation interface {
+	// Validate validates the Parameters in the context of the operation.
+	Validate(p *v1beta1.Parameters, r *Resolver) field.ErrorList
+
+	// Resolve resolves the inputs of the operation from literal Parameters
+	// or from the fields they reference.
+	Resolve(p *v1beta1.Parameters, r *Resolver) error
+
+	// Compute runs the CIDR calculation on the resolved inputs.
+	Compute() error
+
+	// Render returns the computed result as it should be written to the
+	// output field of the composite resource.
+	Render() (any, error)
+}
+
+// An Allocator is a CidrOperation that allocates subnets from one or more
+// pools. The Function reports the utilization of each pool as a metric.
+type Allocator interface {
+	// Allocations returns the subnets allocated from each pool.
+	Allocations() map[netip.Prefix][]netip.Prefix
+}
+
+// A Debugger is a CidrOperation that reports the intermediate values of its
+// calculation in the debug output of the Function.
+type Debugger interface {
+	// Intermediates returns the intermediate values of the calculation.
+	Intermediates() map[string]any
+}
+
+// A CidrOperationFactory creates a new CidrOperation.
+type CidrOperationFactory func() CidrOperation
+
+// A Registry holds the CidrOperations that can be called by name using the
+// cidrFunc or cidrFuncField parameters.
+type Registry struct {
+	mu         sync.RWMutex
+	operations map[string]CidrOperationFactory
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{operations: make(map[string]CidrOperationFactory)}
+}
+
+// DefaultRegistry holds the CidrOperations of the program. It is empty until
+// operations register themselves with it. The built-in cidrFuncs register
+// from the main package of the function, so programs importing this package
+// must register their own.
+var DefaultRegistry = NewRegistry()
+
+// Register adds the named CidrOperation to the registry. It returns an error
+// if an operation with the same name is already registered.
+func (reg *Registry) Register(name string, f CidrOperationFactory) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if name == "" {
+		return errors.New("cannot register a cidrFunc without a name")
+	}
+	if _, ok := reg.operations[name]; ok {
+		return errors.Errorf("cidrFunc %q is already registered", name)
+	}
+	reg.operations[name] = f
+	return nil
+}
+
+// MustRegister adds the named CidrOperation to the registry and panics if
+// it cannot be registered.
+func (reg *Registry) MustRegister(name string, f CidrOperationFactory) {
+	if err := reg.Register(name, f); err != nil {
+		panic(err)
+	}
+}
+
+// Names returns the sorted names of all registered operations.
+func (reg *Registry) Names() []string {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	names := make([]string, 0, len(reg.operations))
+	for name := range reg.operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Supports returns true if an operation with the supplied name is registered.
+func (reg *Registry) Supports(name string) bool {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	_, ok := reg.operations[name]
+	return ok
+}
+
+// New returns a new instance of the named operation.
+func (reg *Registry) New(name string) (CidrOperation, error) {
+	reg.mu.RLock()
+	f, ok := reg.operations[name]
+	reg.mu.RUnlock()
+
+	if !ok {
+		return nil, errors.Errorf("unsupported cidrFunc %q, supported functions are %v", name, reg.Names())
+	}
+	return f(), nil
+}
@@ -0,0 +1,59 @@
+package operation
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+)
+
+// nopOperation is a CidrOperation that does nothing.
+type nopOperation struct{}
+
+func (o *nopOperation) Validate(_ *v1beta1.Parameters, _ *Resolver) field.ErrorList { return nil }
+func (o *nopOperation) Resolve(_ *v1beta1.Parameters, _ *Resolver) error            { return nil }
+func (o *nopOperation) Compute() error                                              { return nil }
+func (o *nopOperation) Render() (any, error)                                        { return nil, nil }
+
+func newNopOperation() CidrOperation { return &nopOperation{} }
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+	reg.MustRegister("nop", newNopOperation)
+	reg.MustRegister("another", newNopOperation)
+
+	if err := reg.Register("nop", newNopOperation); err == nil {
+		t.Errorf("reg.Register(...): expected an error registering a duplicate cidrFunc")
+	}
+	if err := reg.Register("", newNopOperation); err == nil {
+		t.Errorf("reg.Register(...): expected an error registering a cidrFunc without a name")
+	}
+
+	if diff := cmp.Diff([]string{"another", "nop"}, reg.Names()); diff != "" {
+		t.Errorf("reg.Names(): -want, +got:\n%s", diff)
+	}
+	if !reg.Supports("nop") {
+		t.Errorf("reg.Supports(%q): want true, got false", "nop")
+	}
+	if reg.Supports("unknown") {
+		t.Errorf("reg.Supports(%q): want false, got true", "unknown")
+	}
+
+	if op, err := reg.New("nop"); err != nil || op == nil {
+		t.Errorf("reg.New(%q): want an operation, got %v, %v", "nop", op, err)
+	}
+	_, err := reg.New("unknown")
+	if diff := cmp.Diff(`unsupported cidrFunc "unknown", supported functions are [another nop]`, errString(err)); diff != "" {
+		t.Errorf("reg.New(%q): -want err, +got err:\n%s", "unknown", diff)
+	}
+}
+
+// errString returns the message of err, or an empty string if it is nil.
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,124 @@
+package operation
+
+import (
+	"reflect"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// A Resolver resolves operation inputs that are given either as literal
+// Parameters or as references to fields of the observed composite resource.
+type Resolver struct {
+	oxr *resource.Composite
+	req *fnv1.RunFunctionRequest
+
+	resolved []ResolvedParameter
+}
+
+// A ResolvedParameter is an input of a CidrOperation resolved by a Resolver.
+type ResolvedParameter struct {
+	// Name of the parameter.
+	Name string `json:"name"`
+
+	// Field the value was read from. Empty if the literal parameter was used.
+	Field string `json:"field,omitempty"`
+
+	// Value the parameter resolved to.
+	Value any `json:"value"`
+}
+
+// NewResolver returns a Resolver for the supplied request.
+func NewResolver(oxr *resource.Composite, req *fnv1.RunFunctionRequest) *Resolver {
+	return &Resolver{oxr: oxr, req: req}
+}
+
+// Composite returns the observed composite resource. It is nil when the
+// Parameters are validated statically, e.g. by the lint command.
+func (r *Resolver) Composite() *resource.Composite {
+	return r.oxr
+}
+
+// Request returns the RunFunctionRequest inputs are resolved from.
+func (r *Resolver) Request() *fnv1.RunFunctionRequest {
+	return r.req
+}
+
+// Resolved returns the parameters resolved so far, in the order they were
+// resolved.
+func (r *Resolver) Resolved() []ResolvedParameter {
+	return r.resolved
+}
+
+// record records a resolved parameter.
+func (r *Resolver) record(name, fieldPath string, value any) {
+	r.resolved = append(r.resolved, ResolvedParameter{Name: name, Field: fieldPath, Value: value})
+}
+
+// Kind returns the kind of the observed composite resource.
+func (r *Resolver) Kind() string {
+	return r.oxr.Resource.GetKind()
+}
+
+// Prefix returns prefix, or the value of prefixField if it is set. The
+// prefixField may reference the desired state or the pipeline context.
+func (r *Resolver) Prefix(prefix, prefixField string) (string, error) {
+	return r.NamedPrefix("prefix", prefix, prefixField)
+}
+
+// NamedPrefix is like Prefix, but records the prefix under the supplied
+// parameter name.
+func (r *Resolver) NamedPrefix(name, prefix, prefixField string) (string, error) {
+	if prefixField == "" {
+		r.record(name, "", prefix)
+		return prefix, nil
+	}
+	p, err := GetPrefixField(prefixField, r.oxr, r.req)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot get %s from field %s for %s", name, prefixField, r.Kind())
+	}
+	r.record(name, prefixField, p)
+	return p, nil
+}
+
+// Int returns value, or the integer at fieldPath if it is set.
+func (r *Resolver) Int(name string, value int64, fieldPath string) (int64, error) {
+	if fieldPath == "" {
+		r.record(name, "", value)
+		return value, nil
+	}
+	v, err := r.oxr.Resource.GetInteger(fieldPath)
+	if err != nil {
+		return 0, errors.Wrapf(err, "cannot get %s from field %s for %s", name, fieldPath, r.Kind())
+	}
+	r.record(name, fieldPath, v)
+	return v, nil
+}
+
+// String returns value, or the string at fieldPath if it is set.
+func (r *Resolver) String(name, value, fieldPath string) (string, error) {
+	if fieldPath == "" {
+		r.record(name, "", value)
+		return value, nil
+	}
+	v, err := r.oxr.Resource.GetString(fieldPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot get %s from field %s for %s", name, fieldPath, r.Kind())
+	}
+	r.record(name, fieldPath, v)
+	return v, nil
+}
+
+// Into decodes the value at fieldPath into the supplied pointer if fieldPath
+// is set. The pointer is left untouched otherwise.
+func (r *Resolver) Into(name, fieldPath string, into any) error {
+	if fieldPath != "" {
+		if err := r.oxr.Resource.GetValueInto(fieldPath, into); err != nil {
+			return errors.Wrapf(err, "cannot get %s from field %s for %s", name, fieldPath, r.Kind())
+		}
+	}
+	r.record(name, fieldPath, reflect.ValueOf(into).Elem().Interface())
+	return nil
+}
@@ -0,0 +1,170 @@
+package operation
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/request"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// newTestResolver returns a Resolver for a request with the supplied observed
+// composite resource.
+func newTestResolver(t *testing.T, xr string) *Resolver {
+	t.Helper()
+	req := &fnv1.RunFunctionRequest{
+		Observed: &fnv1.State{
+			Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+		},
+	}
+	oxr, err := request.GetObservedCompositeResource(req)
+	if err != nil {
+		t.Fatalf("request.GetObservedCompositeResource(...): %v", err)
+	}
+	return NewResolver(oxr, req)
+}
+
+const testXR = `{
+	"apiVersion": "example.crossplane.io/v1",
+	"kind": "XNetwork",
+	"spec": {"prefix": "10.0.0.0/16", "count": 3, "func": "cidrsubnet", "items": ["a", "b"]}
+}`
+
+func TestResolverPrefix(t *testing.T) {
+	type args struct {
+		prefix      string
+		prefixField string
+	}
+	type want struct {
+		prefix string
+		err    string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Literal": {
+			reason: "should return the literal prefix if no field is set",
+			args:   args{prefix: "192.168.0.0/16"},
+			want:   want{prefix: "192.168.0.0/16"},
+		},
+		"Field": {
+			reason: "should return the prefix at the field",
+			args:   args{prefixField: "spec.prefix"},
+			want:   want{prefix: "10.0.0.0/16"},
+		},
+		"MissingField": {
+			reason: "should fail if the field does not exist",
+			args:   args{prefixField: "spec.missing"},
+			want:   want{err: "cannot get prefix from field spec.missing for XNetwork: cannot get prefix from field spec.missing for XNetwork: spec.missing: no such field"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			prefix, err := newTestResolver(t, testXR).Prefix(tc.args.prefix, tc.args.prefixField)
+			if diff := cmp.Diff(tc.want.prefix, prefix); diff != "" {
+				t.Errorf("%s\nPrefix(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, errString(err)); diff != "" {
+				t.Errorf("%s\nPrefix(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestResolverInt(t *testing.T) {
+	type args struct {
+		value     int64
+		fieldPath string
+	}
+	type want struct {
+		value int64
+		err   string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Literal": {
+			reason: "should return the literal value if no field is set",
+			args:   args{value: 2},
+			want:   want{value: 2},
+		},
+		"Field": {
+			reason: "should return the integer at the field",
+			args:   args{value: 2, fieldPath: "spec.count"},
+			want:   want{value: 3},
+		},
+		"NotAnInteger": {
+			reason: "should fail if the field is not an integer",
+			args:   args{fieldPath: "spec.func"},
+			want:   want{err: "cannot get netNumCount from field spec.func for XNetwork: spec.func: not a (int64) number"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			value, err := newTestResolver(t, testXR).Int("netNumCount", tc.args.value, tc.args.fieldPath)
+			if diff := cmp.Diff(tc.want.value, value); diff != "" {
+				t.Errorf("%s\nInt(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, errString(err)); diff != "" {
+				t.Errorf("%s\nInt(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestResolverString(t *testing.T) {
+	r := newTestResolver(t, testXR)
+
+	if got, err := r.String("cidrFunc", "cidrhost", ""); err != nil || got != "cidrhost" {
+		t.Errorf("String(...): want cidrhost, got %q, %v", got, err)
+	}
+	if got, err := r.String("cidrFunc", "cidrhost", "spec.func"); err != nil || got != "cidrsubnet" {
+		t.Errorf("String(...): want cidrsubnet, got %q, %v", got, err)
+	}
+}
+
+func TestResolverInto(t *testing.T) {
+	r := newTestResolver(t, testXR)
+
+	items := []string{"x"}
+	if err := r.Into("netNumItems", "", &items); err != nil {
+		t.Errorf("Into(...): unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"x"}, items); diff != "" {
+		t.Errorf("Into(...): should leave the value untouched if no field is set: -want, +got:\n%s", diff)
+	}
+
+	if err := r.Into("netNumItems", "spec.items", &items); err != nil {
+		t.Errorf("Into(...): unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a", "b"}, items); diff != "" {
+		t.Errorf("Into(...): should decode the value at the field: -want, +got:\n%s", diff)
+	}
+}
+
+func TestResolverResolved(t *testing.T) {
+	r := newTestResolver(t, testXR)
+	_, _ = r.Prefix("", "spec.prefix")
+	_, _ = r.Int("netNumCount", 2, "")
+	items := []string{}
+	_ = r.Into("netNumItems", "spec.items", &items)
+
+	want := []ResolvedParameter{
+		{Name: "prefix", Field: "spec.prefix", Value: "10.0.0.0/16"},
+		{Name: "netNumCount", Value: int64(2)},
+		{Name: "netNumItems", Field: "spec.items", Value: []string{"a", "b"}},
+	}
+	if diff := cmp.Diff(want, r.Resolved()); diff != "" {
+		t.Errorf("Resolved(): -want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/kong"
+	"google.golang.org/protobuf/types/known/structpb"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+)
+
+// RenderCmd runs the Function in-process against an XR and prints the
+// resulting desired XR and results.
+type RenderCmd struct {
+	XR         string `arg:"" type:"existingfile" help:"A YAML file containing the observed composite resource."`
+	Parameters string `arg:"" type:"existingfile" help:"A YAML file containing the Parameters input of the function."`
+
+	Context string `type:"existingfile" placeholder:"PATH" help:"A JSON file containing the pipeline context."`
+	Desired string `type:"existingfile" placeholder:"PATH" help:"A YAML file containing the desired state produced by previous pipeline steps, with a composite and a map of resources."`
+}
+
+// RenderInputs are the inputs of an in-process render.
+type RenderInputs struct {
+	// XR is the observed composite resource.
+	XR map[string]any
+
+	// Parameters is the input of the function.
+	Parameters *v1beta1.Parameters
+
+	// Context is the pipeline context, if any.
+	Context map[string]any
+
+	// Desired is the desired state produced by previous pipeline steps.
+	Desired *DesiredState
+}
+
+// DesiredState is the desired state produced by previous pipeline steps.
+type DesiredState struct {
+	Composite map[string]any            `json:"composite,omitempty"`
+	Resources map[string]map[string]any `json:"resources,omitempty"`
+}
+
+// renderResult is a function result printed by the render command. It mirrors
+// the results printed by crossplane beta render.
+type renderResult struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Step       string `json:"step"`
+	Severity   string `json:"severity"`
+	Reason     string `json:"reason,omitempty"`
+	Message    string `json:"message"`
+}
+
+// Run the render command.
+func (c *RenderCmd) Run(k *kong.Context) error {
+	ri := RenderInputs{Parameters: &v1beta1.Parameters{}}
+
+	var err error
+	if ri.XR, err = readXR(c.XR); err != nil {
+		return err
+	}
+	if err := readYAML(c.Parameters, ri.Parameters); err != nil {
+		return errors.Wrap(err, "cannot read Parameters")
+	}
+	if c.Context != "" {
+		b, err := os.ReadFile(filepath.Clean(c.Context))
+		if err != nil {
+			return errors.Wrap(err, "cannot read context")
+		}
+		if err := json.Unmarshal(b, &ri.Context); err != nil {
+			return errors.Wrap(err, "cannot decode context")
+		}
+	}
+	if c.Desired != "" {
+		ri.Desired = &DesiredState{}
+		if err := readYAML(c.Desired, ri.Desired); err != nil {
+			return errors.Wrap(err, "cannot read desired state")
+		}
+	}
+
+	rsp, err := Render(context.Background(), &Function{log: logging.NewNopLogger()}, ri)
+	if err != nil {
+		return err
+	}
+	if err := writeRender(k.Stdout, rsp); err != nil {
+		return err
+	}
+	for _, r := range rsp.GetResults() {
+		if r.GetSeverity() == fnv1.Severity_SEVERITY_FATAL {
+			return errors.New("function returned a fatal result")
+		}
+	}
+	return nil
+}
+
+// Request returns the RunFunctionRequest described by the render inputs.
+func (ri RenderInputs) Request() (*fnv1.RunFunctionRequest, error) {
+	req, err := NewRunFunctionRequest(ri.Parameters, ri.XR)
+	if err != nil {
+		return nil, err
+	}
+
+	if ri.Context != nil {
+		if req.Context, err = structpb.NewStruct(ri.Context); err != nil {
+			return nil, errors.Wrap(err, "cannot convert context")
+		}
+	}
+
+	if ri.Desired != nil {
+		req.Desired = &fnv1.State{}
+		if ri.Desired.Composite != nil {
+			dxr, err := structpb.NewStruct(ri.Desired.Composite)
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot convert desired composite resource")
+			}
+			req.Desired.Composite = &fnv1.Resource{Resource: dxr}
+		}
+		for name, r := range ri.Desired.Resources {
+			dcd, err := structpb.NewStruct(r)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot convert desired composed resource %s", name)
+			}
+			if req.Desired.Resources == nil {
+				req.Desired.Resources = make(map[string]*fnv1.Resource)
+			}
+			req.Desired.Resources[name] = &fnv1.Resource{Resource: dcd}
+		}
+	}
+
+	return req, nil
+}
+
+// Render runs the supplied Function in-process with the supplied inputs and
+// returns its response.
+func Render(ctx context.Context, f *Function, ri RenderInputs) (*fnv1.RunFunctionResponse, error) {
+	req, err := ri.Request()
+	if err != nil {
+		return nil, err
+	}
+	return f.RunFunction(ctx, req)
+}
+
+// writeRender writes the desired composite resource and the results of the
+// supplied response to w as a YAML stream.
+func writeRender(w io.Writer, rsp *fnv1.RunFunctionResponse) error {
+	docs := make([]any, 0, len(rsp.GetResults())+1)
+	if dxr := rsp.GetDesired().GetComposite().GetResource(); dxr != nil {
+		docs = append(docs, dxr.AsMap())
+	}
+	for _, r := range rsp.GetResults() {
+		docs = append(docs, renderResult{
+			APIVersion: "render.crossplane.io/v1beta1",
+			Kind:       "Result",
+			Step:       "cidr",
+			Severity:   r.GetSeverity().String(),
+			Reason:     r.GetReason(),
+			Message:    r.GetMessage(),
+		})
+	}
+
+	for _, d := range docs {
+		b, err := yaml.Marshal(d)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+)
+
+func TestRender(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		ri     RenderInputs
+		want   *fnv1.RunFunctionResponse
+	}{
+		"PrefixFromContext": {
+			reason: "should read the prefix from the supplied pipeline context",
+			ri: RenderInputs{
+				XR: map[string]any{"apiVersion": "example.crossplane.io/v1", "kind": "XNetwork"},
+				Parameters: &v1beta1.Parameters{
+					CidrFunc:    "cidrsubnets",
+					PrefixField: "context.network.cidrBlock",
+					NewBits:     []int{2, 2},
+				},
+				Context: map[string]any{"network": map[string]any{"cidrBlock": "10.0.0.0/20"}},
+			},
+			want: &fnv1.RunFunctionResponse{
+				Context: resource.MustStructJSON(`{"network": {"cidrBlock": "10.0.0.0/20"}}`),
+				Desired: &fnv1.State{
+					Composite: &fnv1.Resource{
+						Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": ["10.0.0.0/22", "10.0.4.0/22"]}}}`),
+					},
+				},
+				Conditions: []*fnv1.Condition{
+					{
+						Type:    "CIDRsAllocated",
+						Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+						Reason:  "Allocated",
+						Message: ptr("cidrFunc cidrsubnets wrote its result to status.atFunction.cidr"),
+						Target:  fnv1.Target_TARGET_COMPOSITE.Enum(),
+					},
+				},
+				Meta: &fnv1.ResponseMeta{
+					Ttl: &durationpb.Duration{
+						Seconds: 60,
+					},
+				},
+			},
+		},
+		"PrefixFromDesiredResource": {
+			reason: "should read the prefix from a desired composed resource and keep it in the desired state",
+			ri: RenderInputs{
+				XR: map[string]any{"apiVersion": "example.crossplane.io/v1", "kind": "XNetwork"},
+				Parameters: &v1beta1.Parameters{
+					CidrFunc:    "cidrnetmask",
+					PrefixField: "desired.resources.vpc.resource.spec.forProvider.cidrBlock",
+				},
+				Desired: &DesiredState{
+					Resources: map[string]map[string]any{
+						"vpc": {"spec": map[string]any{"forProvider": map[string]any{"cidrBlock": "10.0.0.0/16"}}},
+					},
+				},
+			},
+			want: &fnv1.RunFunctionResponse{
+				Desired: &fnv1.State{
+					Composite: &fnv1.Resource{
+						Resource: resource.MustStructJSON(`{"apiVersion":"example.crossplane.io/v1","kind":"XNetwork","status": {"atFunction": {"cidr": "255.255.0.0"}}}`),
+					},
+					Resources: map[string]*fnv1.Resource{
+						"vpc": {Resource: resource.MustStructJSON(`{"spec": {"forProvider": {"cidrBlock": "10.0.0.0/16"}}}`)},
+					},
+				},
+				Conditions: []*fnv1.Condition{
+					{
+						Type:    "CIDRsAllocated",
+						Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+						Reason:  "Allocated",
+						Message: ptr("cidrFunc cidrnetmask wrote its result to status.atFunction.cidr"),
+						Target:  fnv1.Target_TARGET_COMPOSITE.Enum(),
+					},
+				},
+				Meta: &fnv1.ResponseMeta{
+					Ttl: &durationpb.Duration{
+						Seconds: 60,
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rsp, err := Render(context.Background(), &Function{log: logging.NewNopLogger()}, tc.ri)
+			if err != nil {
+				t.Fatalf("Render(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, rsp, protocmp.Transform()); diff != "" {
+				t.Errorf("%s\nRender(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRenderCmd(t *testing.T) {
+	out := &bytes.Buffer{}
+	parser := kong.Must(&CLI{}, kong.Writers(out, out))
+	ctx, err := parser.Parse([]string{"render", "examples/xr-cidrsubnet.yaml", "testdata/parameters-cidrsubnets-desired.yaml", "--desired", "testdata/desired.yaml"})
+	if err != nil {
+		t.Fatalf("parser.Parse(...): %v", err)
+	}
+	if err := ctx.Run(); err != nil {
+		t.Fatalf("ctx.Run(): %v", err)
+	}
+
+	want := `---
+apiVersion: platform.upbound.io/v1alpha1
+kind: XCIDR
+status:
+  atFunction:
+    cidr:
+      partitions:
+      - 10.0.0.0/21
+      - 10.0.8.0/21
+      private:
+        subnets:
+        - 10.0.0.0/22
+        - 10.0.4.0/22
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("ctx.Run(): -want, +got:\n%s", diff)
+	}
+}
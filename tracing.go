@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// tracerName is the name of the OpenTelemetry tracer of the Function.
+const tracerName = "github.com/upbound/function-cidr"
+
+// serviceName is the service name traces are exported with.
+const serviceName = "function-cidr"
+
+// Span attributes.
+const (
+	attrCidrFunc     = "cidr.func"
+	attrXRAPIVersion = "crossplane.xr.apiversion"
+	attrXRKind       = "crossplane.xr.kind"
+	attrXRName       = "crossplane.xr.name"
+)
+
+// NewTracerProvider returns a TracerProvider that exports spans to the
+// supplied OTLP gRPC endpoint. The endpoint is either a host and port, or a
+// URL whose scheme selects whether to use TLS.
+func NewTracerProvider(ctx context.Context, endpoint string, insecure bool) (*sdktrace.TracerProvider, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if strings.Contains(endpoint, "://") {
+		opts = []otlptracegrpc.Option{otlptracegrpc.WithEndpointURL(endpoint)}
+	}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	exp, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create OTLP trace exporter")
+	}
+
+	res, err := sdkresource.Merge(sdkresource.Default(), sdkresource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create trace resource")
+	}
+
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res)), nil
+}
+
+// compositeAttributes returns the span attributes identifying the supplied
+// composite resource.
+func compositeAttributes(xr *resource.Composite) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String(attrXRAPIVersion, xr.Resource.GetAPIVersion()),
+		attribute.String(attrXRKind, xr.Resource.GetKind()),
+		attribute.String(attrXRName, xr.Resource.GetName()),
+	}
+}
+
+// endSpan ends the supplied span. If err is not nil it is recorded and the
+// span is marked as failed.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// fatalError returns the message of the first fatal result of the supplied
+// response as an error, or nil if there is none.
+func fatalError(rsp *fnv1.RunFunctionResponse) error {
+	for _, r := range rsp.GetResults() {
+		if r.GetSeverity() == fnv1.Severity_SEVERITY_FATAL {
+			return errors.New(r.GetMessage())
+		}
+	}
+	return nil
+}
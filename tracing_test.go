@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestTracing(t *testing.T) {
+	type span struct {
+		Name   string
+		Status codes.Code
+		Attrs  map[attribute.Key]string
+	}
+
+	cases := map[string]struct {
+		reason string
+		input  string
+		want   []span
+	}{
+		"Success": {
+			reason: "should trace every step of the function annotated with the XR and cidrFunc",
+			input:  `{"apiVersion": "cidr.fn.crossplane.io/v1beta1", "kind": "Parameters", "cidrFunc": "cidrhost", "prefix": "10.0.0.0/24", "hostNum": 5}`,
+			want: []span{
+				{Name: "DecodeInput", Attrs: map[attribute.Key]string{}},
+				{Name: "ValidateParameters", Attrs: map[attribute.Key]string{}},
+				{Name: "Resolve", Attrs: map[attribute.Key]string{attrCidrFunc: "cidrhost"}},
+				{Name: "Compute", Attrs: map[attribute.Key]string{attrCidrFunc: "cidrhost"}},
+				{Name: "RunFunction", Attrs: map[attribute.Key]string{
+					attrCidrFunc:     "cidrhost",
+					attrXRAPIVersion: "example.crossplane.io/v1",
+					attrXRKind:       "XNetwork",
+					attrXRName:       "network",
+				}},
+			},
+		},
+		"ComputeError": {
+			reason: "should mark the failing span and the run as failed",
+			input:  `{"apiVersion": "cidr.fn.crossplane.io/v1beta1", "kind": "Parameters", "cidrFunc": "cidrhost", "prefix": "10.0.0.0/24", "hostNum": 300}`,
+			want: []span{
+				{Name: "DecodeInput", Attrs: map[attribute.Key]string{}},
+				{Name: "ValidateParameters", Attrs: map[attribute.Key]string{}},
+				{Name: "Resolve", Attrs: map[attribute.Key]string{attrCidrFunc: "cidrhost"}},
+				{Name: "Compute", Status: codes.Error, Attrs: map[attribute.Key]string{attrCidrFunc: "cidrhost"}},
+				{Name: "RunFunction", Status: codes.Error, Attrs: map[attribute.Key]string{
+					attrCidrFunc:     "cidrhost",
+					attrXRAPIVersion: "example.crossplane.io/v1",
+					attrXRKind:       "XNetwork",
+					attrXRName:       "network",
+				}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+			f := &Function{log: logging.NewNopLogger(), tracer: tp.Tracer(tracerName)}
+
+			req := &fnv1.RunFunctionRequest{
+				Input: resource.MustStructJSON(tc.input),
+				Observed: &fnv1.State{
+					Composite: &fnv1.Resource{
+						Resource: resource.MustStructJSON(`{"apiVersion": "example.crossplane.io/v1", "kind": "XNetwork", "metadata": {"name": "network"}}`),
+					},
+				},
+			}
+			if _, err := f.RunFunction(context.Background(), req); err != nil {
+				t.Fatalf("RunFunction(...): %v", err)
+			}
+
+			got := make([]span, 0, len(sr.Ended()))
+			for _, s := range sr.Ended() {
+				attrs := map[attribute.Key]string{}
+				for _, a := range s.Attributes() {
+					attrs[a.Key] = a.Value.AsString()
+				}
+				got = append(got, span{Name: s.Name(), Status: s.Status().Code, Attrs: attrs})
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nRunFunction(...): -want spans, +got spans:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
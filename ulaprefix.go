@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
+)
+
+// maxULASubnets is the largest netNumCount of ulaprefix. Every subnet is
+// computed and written to the composite resource, so their number is limited
+// even if newBits allows for many more.
+const maxULASubnets = 1024
+
+func init() {
+	operation.DefaultRegistry.MustRegister("ulaprefix", func() operation.CidrOperation { return &ulaPrefixOperation{} })
+}
+
+// ValidateULAPrefixParameters validates the Parameters object
+// in the context of ulaprefix
+func ValidateULAPrefixParameters(p *v1beta1.Parameters) field.ErrorList {
+	path := field.NewPath("parameters")
+	var errs field.ErrorList
+
+	if p.Seed != "" && p.SeedField != "" {
+		errs = append(errs, field.Forbidden(path.Child("seedField"), "specify only one of seed or seedField to avoid ambiguous function input"))
+	}
+	if p.Prefix != "" || p.PrefixField != "" {
+		errs = append(errs, field.Forbidden(path.Child("prefix"), "cidrFunc ulaprefix derives its prefix from the seed"))
+	}
+
+	switch {
+	case len(p.NewBits) > 0 && len(p.NewBitsField) > 0:
+		errs = append(errs, field.Forbidden(path.Child("newBitsField"), "specify only one of newbits or newbitsfield to avoid ambiguous function input"))
+	case len(p.NewBits) > 1:
+		errs = append(errs, field.Invalid(path.Child("newBits"), p.NewBits, "cidrFunc ulaprefix requires at most 1 parameter in the array"))
+	}
+	errs = append(errs, validateNewBits(path.Child("newBits"), p.NewBits, 1, cidr.Bits128)...)
+
+	if p.NetNumCount > 0 && p.NetNumCountField != "" {
+		errs = append(errs, field.Forbidden(path.Child("netNumCountField"), "specify only one of netNumCount or netNumCountField to avoid ambiguous function input"))
+	}
+	switch {
+	case p.NetNumCount > maxULASubnets:
+		errs = append(errs, field.Invalid(path.Child("netNumCount"), p.NetNumCount, fmt.Sprintf("netNumCount must not exceed %d", maxULASubnets)))
+	case len(p.NewBits) == 1 && !netNumCountFits(p.NetNumCount, p.NewBits[0]):
+		errs = append(errs, field.Invalid(path.Child("netNumCount"), p.NetNumCount, "netNumCount must not exceed the number of subnets of newBits"))
+	}
+	if p.NetNum > 0 && p.NetNumField != "" {
+		errs = append(errs, field.Forbidden(path.Child("netNumField"), "specify only one of netNum or netNumField to avoid ambiguous function input"))
+	}
+
+	return errs
+}
+
+// ulaPrefixOperation derives an RFC 4193 Unique Local Address prefix from a
+// seed and optionally calculates subnets of it.
+type ulaPrefixOperation struct {
+	seed        string
+	newBits     []int
+	netNum      int64
+	netNumCount int64
+	prefix      netip.Prefix
+	subnets     []netip.Prefix
+}
+
+func (o *ulaPrefixOperation) Validate(p *v1beta1.Parameters, _ *operation.Resolver) field.ErrorList {
+	return ValidateULAPrefixParameters(p)
+}
+
+func (o *ulaPrefixOperation) Resolve(p *v1beta1.Parameters, r *operation.Resolver) error {
+	var err error
+	seedField := p.SeedField
+	if p.Seed == "" && seedField == "" {
+		seedField = "metadata.uid"
+	}
+	if o.seed, err = r.String("seed", p.Seed, seedField); err != nil {
+		return err
+	}
+	if o.seed == "" {
+		return errors.Errorf("cidrFunc ulaprefix requires a non-empty seed for %s", r.Kind())
+	}
+
+	o.newBits = p.NewBits
+	if err := r.Into("newBits", p.NewBitsField, &o.newBits); err != nil {
+		return err
+	}
+	if len(o.newBits) == 0 {
+		return nil
+	}
+	if o.netNum, err = r.Int("netNum", p.NetNum, p.NetNumField); err != nil {
+		return err
+	}
+	if o.netNumCount, err = r.Int("netNumCount", p.NetNumCount, p.NetNumCountField); err != nil {
+		return err
+	}
+	if o.netNumCount > maxULASubnets {
+		return errors.Errorf("cidrFunc ulaprefix requires a netNumCount of at most %d for %s", maxULASubnets, r.Kind())
+	}
+	if !netNumCountFits(o.netNumCount, o.newBits[0]) {
+		return errors.Errorf("cidrFunc ulaprefix requires a netNumCount of at most the number of subnets of newBits for %s", r.Kind())
+	}
+	return nil
+}
+
+// netNumCountFits returns true if a prefix extended by newBits has at least
+// count subnets. Any count fits 63 or more newBits.
+func netNumCountFits(count int64, newBits int) bool {
+	return newBits >= 63 || count <= int64(1)<<newBits
+}
+
+func (o *ulaPrefixOperation) Compute() error {
+	o.prefix = cidr.ULAPrefix(o.seed)
+	if len(o.newBits) == 0 {
+		return nil
+	}
+
+	for i := range max(o.netNumCount, 1) {
+		subnet, err := cidr.Subnet(o.prefix, o.newBits[0], o.netNum+i)
+		if err != nil {
+			return err
+		}
+		o.subnets = append(o.subnets, subnet)
+	}
+	return nil
+}
+
+// Render returns the prefix, or the prefix and its subnets if newBits is
+// specified.
+func (o *ulaPrefixOperation) Render() (any, error) {
+	if len(o.newBits) == 0 {
+		return o.prefix.String(), nil
+	}
+	return map[string]any{"prefix": o.prefix.String(), "subnets": prefixStrings(o.subnets)}, nil
+}
+
+func (o *ulaPrefixOperation) Allocations() map[netip.Prefix][]netip.Prefix {
+	if len(o.subnets) == 0 {
+		return nil
+	}
+	return map[netip.Prefix][]netip.Prefix{o.prefix: o.subnets}
+}
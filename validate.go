@@ -1,290 +1,133 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"net"
-	"strings"
 
 	"github.com/pkg/errors"
 
-	"github.com/tidwall/gjson"
-
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
-	"github.com/crossplane/function-sdk-go/request"
 	"github.com/crossplane/function-sdk-go/resource"
 
 	"github.com/upbound/function-cidr/input/v1beta1"
+	"github.com/upbound/function-cidr/pkg/cidr"
+	"github.com/upbound/function-cidr/pkg/operation"
 )
 
-// ExtractKeys extracts keys from a dotted list of keys while considering quoted strings a single value.
-func ExtractKeys(input string) []string {
-	var keys []string
-	var keyBuilder strings.Builder
-	inQuotes := false
-
-	for i := 0; i < len(input); i++ {
-		char := input[i]
-
-		if char == '\'' {
-			inQuotes = !inQuotes
-		} else if char == '.' && !inQuotes {
-			keys = append(keys, keyBuilder.String())
-			keyBuilder.Reset()
-		} else {
-			keyBuilder.WriteByte(char)
-		}
-	}
-
-	if keyBuilder.Len() > 0 {
-		keys = append(keys, keyBuilder.String())
-	}
-
-	return keys
-}
-
-// GetPrefixField returns the prefix value from the defined field
-func GetPrefixField(prefixField string, oxr *resource.Composite, req *fnv1.RunFunctionRequest) (string, error) {
-	prefix := ""
-	if strings.HasPrefix(prefixField, "desired.") {
-		if strings.HasPrefix(prefixField, "desired.composite.") {
-			dxr, err := request.GetDesiredCompositeResource(req)
-			if err != nil {
-				return "", errors.Wrapf(err, "cannot get desired composite resource from %s for %s", prefixField, dxr.Resource.GetKind())
-			}
-			dxrPrefix, err := dxr.Resource.GetString(strings.Replace(prefixField, "desired.composite.resource.", "", 1))
-			prefix = dxrPrefix
-			if err != nil {
-				return "", errors.Wrapf(err, "cannot get prefix from field %s for %s", prefixField, dxr.Resource.GetKind())
-			}
-		} else if strings.HasPrefix(prefixField, "desired.resources.") {
-			properties := ExtractKeys(strings.Replace(prefixField, "desired.resources.", "", 1))
-			resourceName := resource.Name(properties[0])
-			dxr, err := request.GetDesiredComposedResources(req)
-			if err != nil {
-				return "", errors.Wrapf(err, "cannot get desired composed resource from %s", prefixField)
-			}
-			if val, ok := dxr[resourceName]; ok {
-				dxrPrefix, err := val.Resource.GetString(strings.Replace(prefixField, "desired.resources."+properties[0]+".resource.", "", 1))
-				prefix = dxrPrefix
-				if err != nil {
-					return "", errors.Wrapf(err, "cannot get prefix for resource with name %s from field %s", resourceName, prefixField)
-				}
-			} else {
-				return "", errors.New(fmt.Sprintf("No composed resource with name %s found for field %s", resourceName, prefixField))
-			}
-		}
-	} else if strings.HasPrefix(prefixField, "context.") {
-		ctxField := strings.Replace(prefixField, "context.", "", 1)
-		ctx := req.Context
-		if ctx == nil {
-			return "", errors.New("No context available")
-		}
-		json, err := json.Marshal(ctx)
-		if err != nil {
-			return "", errors.Wrapf(err, "failed to marshall context to json for extraction of field %s", prefixField)
-		}
-		prefixValue := gjson.GetBytes(json, ctxField)
-		if !prefixValue.Exists() {
-			return "", errors.New(fmt.Sprintf("Failed to extract value for %s from json context %s", ctxField, json))
-		}
-		prefix = prefixValue.Str
-	} else {
-		prefixValue, err := oxr.Resource.GetString(prefixField)
-		prefix = prefixValue
-		if err != nil {
-			return "", errors.Wrapf(err, "cannot get prefix from field %s for %s", prefixField, oxr.Resource.GetKind())
-		}
-	}
-	return prefix, nil
+// ValidatePrefixParameter validates prefix parameter. The prefixField is only
+// resolved if oxr is not nil.
+func ValidatePrefixParameter(prefix, prefixField string, oxr *resource.Composite, req *fnv1.RunFunctionRequest) field.ErrorList {
+	return validateNamedPrefix("prefix", prefix, prefixField, oxr, req)
 }
 
-// ValidatePrefixParameter validates prefix parameter
-func ValidatePrefixParameter(prefix, prefixField string, oxr *resource.Composite, req *fnv1.RunFunctionRequest) *field.Error {
+// validateNamedPrefix validates a prefix parameter of the supplied name and
+// its name+Field counterpart. The field is only resolved if oxr is not nil.
+func validateNamedPrefix(name, prefix, prefixField string, oxr *resource.Composite, req *fnv1.RunFunctionRequest) field.ErrorList {
+	path := field.NewPath("parameters")
 	if len(prefix) > 0 && len(prefixField) > 0 {
-		return field.Required(field.NewPath("parameters"), "specify only one of prefix or prefixField to avoid ambiguous function input")
+		return field.ErrorList{field.Forbidden(path.Child(name+"Field"), fmt.Sprintf("specify only one of %s or %sField to avoid ambiguous function input", name, name))}
 	}
 	if prefix == "" {
 		if prefixField == "" {
-			return field.Required(field.NewPath("parameters"), "either prefix or prefixField function input is required")
+			return field.ErrorList{field.Required(path.Child(name), fmt.Sprintf("either %s or %sField function input is required", name, name))}
 		}
-		oxrPrefix, err := GetPrefixField(prefixField, oxr, req)
-		prefix = oxrPrefix
-		if err != nil {
-			return field.Required(field.NewPath("parameters"), errors.Wrapf(err, "cannot get prefix at prefixField %s", prefixField).Error())
-		}
-	}
-
-	_, _, err := net.ParseCIDR(prefix)
-	if err != nil {
-		return field.Required(field.NewPath("parameters"), "invalid CIDR prefix address "+prefix)
-	}
-	return nil
-}
-
-// ValidateCidrHostParameters validates the Parameters object
-// in the context of cidrhost
-func ValidateCidrHostParameters(p *v1beta1.Parameters, oxr resource.Composite) *field.Error {
-	if p.HostNum > 0 && len(p.HostNumField) > 0 {
-		return field.Required(field.NewPath("parameters"), "specify only one of hostnum or hostnumfield to avoid ambiguous function input")
-	}
-	hostNum := p.HostNum
-	if hostNum == 0 {
-		if p.HostNumField == "" {
-			return field.Required(field.NewPath("parameters"), "either hostnum or hostnumfield function input is required")
+		if oxr == nil {
+			return nil
 		}
-		_, err := oxr.Resource.GetInteger(p.HostNumField)
+		oxrPrefix, err := operation.GetPrefixField(prefixField, oxr, req)
 		if err != nil {
-			return field.Required(field.NewPath("parameters"), "cannot get hostnum at hostnumfield "+p.HostNumField)
-		}
-	}
-
-	return nil
-}
-
-// ValidateCidrSubnetParameters validates the Parameters object
-// in the context of cidrsubnet
-func ValidateCidrSubnetParameters(p *v1beta1.Parameters) *field.Error {
-	if len(p.NewBits) > 0 && len(p.NewBitsField) > 0 {
-		return field.Required(field.NewPath("parameters"), "specify only one of newbits or newbitsfield to avoid ambiguous function input")
-	}
-	if len(p.NewBits) == 0 && p.NewBitsField == "" {
-		return field.Required(field.NewPath("parameters"), "either newbits or newbitsfield function input is required")
-	}
-
-	if p.NewBitsField == "" {
-		if len(p.NewBits) != 1 {
-			return field.Required(field.NewPath("parameters"), "cidrFunc cidrsubnet requires exactly 1 parameter in the array")
+			return field.ErrorList{field.Invalid(path.Child(name+"Field"), prefixField, errors.Wrapf(err, "cannot get %s", name).Error())}
 		}
+		prefix = oxrPrefix
+		path = path.Child(name + "Field")
+	} else {
+		path = path.Child(name)
 	}
 
-	if p.NetNum > 0 && len(p.NetNumField) > 0 {
-		return field.Required(field.NewPath("parameters"), "cidrFunc cidrsubnet requires either one of netnum or netnumfield")
+	if _, err := cidr.ParsePrefix(prefix); err != nil {
+		return field.ErrorList{field.Invalid(path, prefix, "invalid CIDR prefix address")}
 	}
-
 	return nil
 }
 
-// ValidateCidrSubnetsParameters validates the Parameters object
-// in the context of cidrsubnet
-func ValidateCidrSubnetsParameters(p *v1beta1.Parameters, oxr resource.Composite) *field.Error {
-	var newBits []int
-	if len(p.NewBits) > 0 && len(p.NewBitsField) > 0 {
-		return field.Required(field.NewPath("parameters"), "cidrFunc cidrsubnets requires either one of newbits or newbitsfield")
-	}
-
-	if len(p.NewBitsField) > 0 {
-		err := oxr.Resource.GetValueInto(p.NewBitsField, &newBits)
-		if err != nil {
-			return field.Required(field.NewPath("parameters"), "cannot get newbits at newbitsfield "+p.NewBitsField)
+// validateNewBits validates that every newBits element extends a prefix by
+// at least minBits and at most maxBits bits.
+func validateNewBits(path *field.Path, newBits []int, minBits, maxBits int) field.ErrorList {
+	var errs field.ErrorList
+	for i, nb := range newBits {
+		if nb < minBits || nb > maxBits {
+			errs = append(errs, field.Invalid(path.Index(i), nb, fmt.Sprintf("newBits must be between %d and %d", minBits, maxBits)))
 		}
 	}
-
-	return nil
+	return errs
 }
 
-// ValidateCidrSubnetloopParameters validates the Parameters object
-// in the context of cidrsubnetloop
-func ValidateCidrSubnetloopParameters(p *v1beta1.Parameters) *field.Error {
-	if p.NetNumCount > 0 && len(p.NetNumCountField) > 0 {
-		// only one of netnumcount or NetNumCountField
-		errStr := "cidrFunc cidrsubnetloop requires either one of netnumcount or netnumcountfield, "
-		errStr += "but only if nonetnumitems or netnumitemsfield have been specified"
-		return field.Required(field.NewPath("parameters"), errStr)
-	}
-	if len(p.NetNumItems) > 0 && len(p.NetNumItemsField) > 0 {
-		// only one of netnumitems or netnumitemsfield
-		errStr := "cidrFunc cidrsubnetloop requires either one of netnumitems or netnumitemsfield, "
-		errStr += "but only if nonetnumcount or netnumcountfield have been specified"
-		return field.Required(field.NewPath("parameters"), errStr)
-	}
-
-	netNumCountSpecified := bool(p.NetNumCount > 0 || len(p.NetNumCountField) > 0)
-	netNumItemsSpecified := bool(len(p.NetNumItems) > 0 || len(p.NetNumItemsField) > 0)
-	if netNumCountSpecified && netNumItemsSpecified {
-		// only either netnumcount or items
-		errStr := "cidrFunc cidrsubnetloop requires either one of netnumitems or netnumitemsfield, "
-		errStr += "or mutually exclusive one of netnumcount or netnumcountfield, but not both counts and items"
-		return field.Required(field.NewPath("parameters"), errStr)
-	}
-	if len(p.NewBits) > 0 && len(p.NewBitsField) > 0 {
-		return field.Required(field.NewPath("parameters"), "cidrFunc cidrsubnetloop requires either one of newbits or newbitsfield")
-	}
-	if p.Offset > 0 && len(p.OffsetField) > 0 {
-		return field.Required(field.NewPath("parameters"), "cidrFunc cidrsubnetloop requires either one of offset or offsetfield")
+// addressBits returns the address length of the supplied prefix. A prefix
+// that is not set, e.g. because it is read from a field, or that is invalid
+// may be an IPv6 prefix, so its address length is that of IPv6.
+func addressBits(prefix string) int {
+	p, err := cidr.ParsePrefix(prefix)
+	if err != nil {
+		return cidr.Bits128
 	}
-
-	return nil
+	return p.Addr().BitLen()
 }
 
-func ValidateMultiCidrPrefixParameter(p *v1beta1.Parameters, oxr *resource.Composite) *field.Error {
-	if len(p.MultiPrefix) > 0 && len(p.MultiPrefixField) > 0 {
-		return field.Required(field.NewPath("parameters"), "specify only one of multiPrefix or multiPrefixField to avoid ambiguous function input")
-	}
-
-	if len(p.MultiPrefix) == 0 && p.MultiPrefixField == "" {
-		return field.Required(field.NewPath("parameters"), "either multiPrefix or multiPrefixField function input is required")
+// validateUsedCIDRs validates the usedCIDRs parameter and its usedCIDRsField
+// counterpart.
+func validateUsedCIDRs(p *v1beta1.Parameters) field.ErrorList {
+	path := field.NewPath("parameters")
+	var errs field.ErrorList
+	if len(p.UsedCIDRs) > 0 && p.UsedCIDRsField != "" {
+		errs = append(errs, field.Forbidden(path.Child("usedCIDRsField"), "specify only one of usedCIDRs or usedCIDRsField to avoid ambiguous function input"))
 	}
-
-	multiPrefixes := p.MultiPrefix
-	if len(p.MultiPrefix) == 0 {
-		err := oxr.Resource.GetValueInto(p.MultiPrefixField, &multiPrefixes)
-		if err != nil {
-			return field.Required(field.NewPath("parameters"), "cannot get multiPrefixes at multiPrefixField "+p.MultiPrefixField)
-		}
-	}
-
-	for _, mp := range multiPrefixes {
-		_, _, err := net.ParseCIDR(mp.Prefix)
-		if err != nil {
-			return field.Required(field.NewPath("parameters"), "invalid CIDR prefix address "+mp.Prefix)
-		}
-
-		if len(mp.NewBits) == 0 {
-			return field.Required(field.NewPath("parameters"), "newBits is required for each prefix in multiPrefixField")
+	for i, u := range p.UsedCIDRs {
+		if _, err := cidr.ParsePrefix(u); err != nil {
+			errs = append(errs, field.Invalid(path.Child("usedCIDRs").Index(i), u, "invalid CIDR prefix address"))
 		}
 	}
+	return errs
+}
 
-	return nil
+// ValidateParameters validates the Parameters object against the built-in
+// cidrFuncs and returns every problem found rather than stopping at the first
+// one.
+func ValidateParameters(p *v1beta1.Parameters, oxr *resource.Composite, req *fnv1.RunFunctionRequest) field.ErrorList {
+	return validateParameters(operation.DefaultRegistry, p, oxr, req)
 }
 
-// ValidateParameters validates the Parameters object.
-func ValidateParameters(p *v1beta1.Parameters, oxr *resource.Composite, req *fnv1.RunFunctionRequest) *field.Error {
+// validateParameters validates the Parameters object against the cidrFuncs of
+// the supplied registry and returns every problem found rather than stopping
+// at the first one.
+//
+// If oxr is nil only the static parts of the Parameters are validated: *Field
+// parameters are not resolved, and a Parameters object that takes its cidrFunc
+// from a cidrFuncField is not validated any further.
+func validateParameters(reg *operation.Registry, p *v1beta1.Parameters, oxr *resource.Composite, req *fnv1.RunFunctionRequest) field.ErrorList {
+	path := field.NewPath("parameters")
 	cidrFunc := p.CidrFunc
-	var err error
 
 	if p.CidrFuncField != "" {
+		if oxr == nil {
+			return nil
+		}
+		var err error
 		cidrFunc, err = oxr.Resource.GetString(p.CidrFuncField)
 		if err != nil {
-			return field.Required(field.NewPath("parameters"), "cannot get cidrFunc at cidrFuncField "+p.CidrFuncField)
+			return field.ErrorList{field.Invalid(path.Child("cidrFuncField"), p.CidrFuncField, "cannot get cidrFunc at cidrFuncField")}
 		}
 	}
 
-	if cidrFunc != "multiprefixloop" {
-		fieldError := ValidatePrefixParameter(p.Prefix, p.PrefixField, oxr, req)
-		if fieldError != nil {
-			return fieldError
-		}
+	if cidrFunc == "" {
+		return field.ErrorList{field.Required(path.Child("cidrFunc"), "cidrFunc is required")}
 	}
-
-	switch cidrFunc {
-	case "":
-		return field.Required(field.NewPath("parameters"), "cidrFunc is required")
-	case "cidrhost":
-		return ValidateCidrHostParameters(p, *oxr)
-	case "cidrnetmask":
-		return nil // cidrnetmask only relies on prefix which was checked above
-	case "cidrsubnet":
-		return ValidateCidrSubnetParameters(p)
-	case "cidrsubnets":
-		return ValidateCidrSubnetsParameters(p, *oxr)
-	case "cidrsubnetloop":
-		return ValidateCidrSubnetloopParameters(p)
-	case "multiprefixloop":
-		return ValidateMultiCidrPrefixParameter(p, oxr)
-	default:
-		return field.Required(field.NewPath("parameters"), "unexpected cidrFunc "+cidrFunc)
+	op, err := reg.New(cidrFunc)
+	if err != nil {
+		return field.ErrorList{field.NotSupported(path.Child("cidrFunc"), cidrFunc, reg.Names())}
 	}
+
+	errs := op.Validate(p, operation.NewResolver(oxr, req))
+	errs = append(errs, validateDriftChecks(p.DriftChecks)...)
+	return append(errs, validateLock(p)...)
 }